@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/dotbitHQ/das-lib/common"
 	"github.com/dotbitHQ/das-lib/core"
+	"github.com/dotbitHQ/das-lib/molecule"
 	"github.com/dotbitHQ/das-lib/witness"
 	"github.com/scorpiotzh/toolib"
 	"strconv"
@@ -67,7 +68,7 @@ func (b *BlockParser) ActionEditRecords(req FuncTransactionHandleReq) (resp Func
 
 	log.Info("ActionEditRecords:", account, transactionInfo.Address)
 
-	if err := b.dbDao.CreateRecordsInfos(accountInfo, recordsInfos, transactionInfo); err != nil {
+	if err := req.DbDao.CreateRecordsInfos(accountInfo, recordsInfos, transactionInfo); err != nil {
 		log.Error("CreateRecordsInfos err:", err.Error(), toolib.JsonString(transactionInfo))
 		resp.Err = fmt.Errorf("CreateRecordsInfos err: %s", err.Error())
 	}
@@ -121,7 +122,7 @@ func (b *BlockParser) ActionEditManager(req FuncTransactionHandleReq) (resp Func
 
 	log.Info("ActionEditManager:", account, managerHex.DasAlgorithmId, managerHex.ChainType, managerHex.AddressHex, transactionInfo.Address)
 
-	if err := b.dbDao.EditManager(accountInfo, transactionInfo); err != nil {
+	if err := req.DbDao.EditManager(accountInfo, transactionInfo); err != nil {
 		log.Error("EditManager err:", err.Error(), toolib.JsonString(transactionInfo))
 		resp.Err = fmt.Errorf("EditManager err: %s", err.Error())
 	}
@@ -203,7 +204,7 @@ func (b *BlockParser) ActionRenewAccount(req FuncTransactionHandleReq) (resp Fun
 
 	log.Info("ActionRenewAccount:", builder.Account, builder.ExpiredAt, transactionInfo.Capacity)
 
-	if err := b.dbDao.RenewAccount(inputsOutpoints, incomeCellInfos, accountInfo, transactionInfo); err != nil {
+	if err := req.DbDao.RenewAccount(inputsOutpoints, incomeCellInfos, accountInfo, transactionInfo); err != nil {
 		log.Error("RenewAccount err:", err.Error(), toolib.JsonString(transactionInfo))
 		resp.Err = fmt.Errorf("RenewAccount err: %s", err.Error())
 	}
@@ -239,7 +240,7 @@ func (b *BlockParser) ActionTransferAccount(req FuncTransactionHandleReq) (resp
 		resp.Err = fmt.Errorf("AccountCellDataBuilderFromTx err: %s", err.Error())
 		return
 	}
-	res, err := b.dasCore.Client().GetTransaction(b.ctx, req.Tx.Inputs[oldBuilder.Index].PreviousOutput.TxHash)
+	res, err := b.getTransaction(req.Tx.Inputs[oldBuilder.Index].PreviousOutput.TxHash)
 	if err != nil {
 		resp.Err = fmt.Errorf("GetTransaction err: %s", err.Error())
 		return
@@ -290,7 +291,7 @@ func (b *BlockParser) ActionTransferAccount(req FuncTransactionHandleReq) (resp
 
 	log.Info("ActionTransferAccount:", account, oHex.DasAlgorithmId, oHex.ChainType, oHex.AddressHex, mHex.DasAlgorithmId, mHex.ChainType, mHex.AddressHex, transactionInfo.Address)
 
-	if err := b.dbDao.TransferAccount(accountInfo, transactionInfo, recordsInfos); err != nil {
+	if err := req.DbDao.TransferAccount(accountInfo, transactionInfo, recordsInfos); err != nil {
 		log.Error("TransferAccount err:", err.Error(), toolib.JsonString(transactionInfo))
 		resp.Err = fmt.Errorf("TransferAccount err: %s", err.Error())
 	}
@@ -345,7 +346,7 @@ func (b *BlockParser) ActionForceRecoverAccountStatus(req FuncTransactionHandleR
 
 	log.Info("ActionForceRecoverAccountStatus:", builder.Account, oldBuilder.Status, builder.Status)
 
-	if err = b.dbDao.ForceRecoverAccountStatus(oldBuilder.Status, accountInfo, transactionInfo); err != nil {
+	if err = req.DbDao.ForceRecoverAccountStatus(oldBuilder.Status, accountInfo, transactionInfo); err != nil {
 		resp.Err = fmt.Errorf("ForceRecoverAccountStatus err: %s", err.Error())
 		return
 	}
@@ -379,7 +380,7 @@ func (b *BlockParser) ActionRecycleExpiredAccount(req FuncTransactionHandleReq)
 		return
 	}
 
-	res, err := b.dasCore.Client().GetTransaction(b.ctx, req.Tx.Inputs[1].PreviousOutput.TxHash)
+	res, err := b.getTransaction(req.Tx.Inputs[1].PreviousOutput.TxHash)
 	if err != nil {
 		resp.Err = fmt.Errorf("GetTransaction err: %s", err.Error())
 		return
@@ -426,7 +427,7 @@ func (b *BlockParser) ActionRecycleExpiredAccount(req FuncTransactionHandleReq)
 
 	log.Info("ActionRecycleExpiredAccount:", builder.Account, oHex.DasAlgorithmId, oHex.ChainType, oHex.AddressHex)
 
-	if err = b.dbDao.RecycleExpiredAccount(accountInfo, transactionInfo, builder.AccountId, builder.EnableSubAccount); err != nil {
+	if err = req.DbDao.RecycleExpiredAccount(accountInfo, transactionInfo, builder.AccountId, builder.EnableSubAccount); err != nil {
 		resp.Err = fmt.Errorf("RecycleExpiredAccount err: %s", err.Error())
 		return
 	}
@@ -456,7 +457,7 @@ func (b *BlockParser) ActionAccountCrossChain(req FuncTransactionHandleReq) (res
 	}
 	var isTrans bool
 	if req.Action == common.DasActionUnlockAccountForCrossChain {
-		res, err := b.dasCore.Client().GetTransaction(b.ctx, req.Tx.Inputs[0].PreviousOutput.TxHash)
+		res, err := b.getTransaction(req.Tx.Inputs[0].PreviousOutput.TxHash)
 		if err != nil {
 			resp.Err = fmt.Errorf("GetTransaction err: %s", err.Error())
 			return
@@ -492,7 +493,40 @@ func (b *BlockParser) ActionAccountCrossChain(req FuncTransactionHandleReq) (res
 		BlockTimestamp: req.BlockTimestamp,
 	}
 
-	if err = b.dbDao.AccountCrossChain(accountInfo, transactionInfo, isTrans); err != nil {
+	crossChainInfo := dao.TableCrossChainInfo{
+		AccountId: builder.AccountId,
+		Account:   builder.Account,
+	}
+	if req.Action == common.DasActionLockAccountForCrossChain {
+		actionBuilder, err := witness.ActionDataBuilderFromTx(req.Tx)
+		if err != nil {
+			resp.Err = fmt.Errorf("ActionDataBuilderFromTx err: %s", err.Error())
+			return
+		}
+		// params: coin_type, chain_id, role
+		if len(actionBuilder.Params) == 3 {
+			coinType, _ := molecule.Bytes2GoU64(actionBuilder.Params[0])
+			chainId, _ := molecule.Bytes2GoU64(actionBuilder.Params[1])
+			crossChainInfo.CoinType = strconv.FormatUint(coinType, 10)
+			crossChainInfo.ChainId = strconv.FormatUint(chainId, 10)
+		}
+		crossChainInfo.OwnerChainType = ownerHex.ChainType
+		crossChainInfo.Owner = ownerHex.AddressHex
+		crossChainInfo.LockBlockNumber = req.BlockNumber
+		crossChainInfo.LockTimestamp = req.BlockTimestamp
+		crossChainInfo.LockTxHash = req.TxHash
+		crossChainInfo.Status = dao.CrossChainStatusLocked
+	} else {
+		crossChainInfo.UnlockBlockNumber = req.BlockNumber
+		crossChainInfo.UnlockTimestamp = req.BlockTimestamp
+		crossChainInfo.UnlockTxHash = req.TxHash
+		crossChainInfo.UnlockOwnerChainType = ownerHex.ChainType
+		crossChainInfo.UnlockOwner = ownerHex.AddressHex
+		crossChainInfo.IsTrans = isTrans
+		crossChainInfo.Status = dao.CrossChainStatusUnlocked
+	}
+
+	if err = req.DbDao.AccountCrossChain(accountInfo, transactionInfo, crossChainInfo, isTrans); err != nil {
 		log.Error("AccountCrossChain err:", err.Error(), req.TxHash, req.BlockNumber)
 		resp.Err = fmt.Errorf("AccountCrossChain err: %s ", err.Error())
 		return
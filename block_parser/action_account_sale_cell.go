@@ -108,7 +108,7 @@ func (b *BlockParser) ActionStartAccountSale(req FuncTransactionHandleReq) (resp
 
 	log.Info("ActionStartAccountSale:", transactionInfo.Account)
 
-	if err = b.dbDao.StartAccountSale(accountInfo, tradeInfo, tradeHistory, transactionInfo); err != nil {
+	if err = req.DbDao.StartAccountSale(accountInfo, tradeInfo, tradeHistory, transactionInfo); err != nil {
 		resp.Err = fmt.Errorf("StartAccountSale err: %s", err.Error())
 		return
 	}
@@ -192,7 +192,7 @@ func (b *BlockParser) ActionEditAccountSale(req FuncTransactionHandleReq) (resp
 
 	log.Info("ActionEditAccountSale:", transactionInfo.Account)
 
-	if err := b.dbDao.EditAccountSale(tradeInfo, tradeHistory, transactionInfo); err != nil {
+	if err := req.DbDao.EditAccountSale(tradeInfo, tradeHistory, transactionInfo); err != nil {
 		resp.Err = fmt.Errorf("EditAccountSale err: %s", err.Error())
 		return
 	}
@@ -244,7 +244,7 @@ func (b *BlockParser) ActionCancelAccountSale(req FuncTransactionHandleReq) (res
 
 	log.Info("ActionCancelAccountSale:", transactionInfo.Account)
 
-	if err := b.dbDao.CancelAccountSale(accountInfo, transactionInfo); err != nil {
+	if err := req.DbDao.CancelAccountSale(accountInfo, transactionInfo); err != nil {
 		resp.Err = fmt.Errorf("CancelAccountSale err: %s", err.Error())
 		return
 	}
@@ -286,7 +286,7 @@ func (b *BlockParser) ActionBuyAccount(req FuncTransactionHandleReq) (resp FuncT
 	}
 
 	// sale cell
-	res, err := b.dasCore.Client().GetTransaction(b.ctx, req.Tx.Inputs[1].PreviousOutput.TxHash)
+	res, err := b.getTransaction(req.Tx.Inputs[1].PreviousOutput.TxHash)
 	if err != nil {
 		resp.Err = fmt.Errorf("GetTransaction err: %s", err.Error())
 		return
@@ -400,7 +400,7 @@ func (b *BlockParser) ActionBuyAccount(req FuncTransactionHandleReq) (resp FuncT
 
 	log.Info("ActionBuyAccount:", account, len(rebateList))
 
-	if err := b.dbDao.BuyAccount(incomeCellInfos, accountInfo, tradeDealInfo, transactionInfoBuy, transactionInfoSale, rebateList, recordsInfos); err != nil {
+	if err := req.DbDao.BuyAccount(incomeCellInfos, accountInfo, tradeDealInfo, transactionInfoBuy, transactionInfoSale, rebateList, recordsInfos); err != nil {
 		log.Error("BuyAccount err:", err.Error(), toolib.JsonString(transactionInfoBuy), toolib.JsonString(transactionInfoSale))
 		resp.Err = fmt.Errorf("BuyAccount err: %s", err.Error())
 		return
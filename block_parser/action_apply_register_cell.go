@@ -28,7 +28,7 @@ func (b *BlockParser) ActionApplyRegister(req FuncTransactionHandleReq) (resp Fu
 		BlockTimestamp: req.BlockTimestamp,
 	}
 
-	if err := b.dbDao.CreateTransactionInfo(transactionInfo); err != nil {
+	if err := req.DbDao.CreateTransactionInfo(transactionInfo); err != nil {
 		log.Error("CreateTransactionInfo err:", err.Error(), toolib.JsonString(transactionInfo))
 		resp.Err = fmt.Errorf("CreateTransactionInfo err: %s", err.Error())
 		return
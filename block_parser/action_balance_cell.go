@@ -51,7 +51,7 @@ func (b *BlockParser) ActionBalanceCells(req FuncTransactionHandleReq) (resp Fun
 		})
 	}
 
-	if err = b.dbDao.CreateTransactionInfoList(transactionInfos); err != nil {
+	if err = req.DbDao.CreateTransactionInfoList(transactionInfos); err != nil {
 		log.Error("CreateTransactionInfoList err: ", err.Error(), toolib.JsonString(transactionInfos))
 		resp.Err = fmt.Errorf("CreateTransactionInfoList err: %s", err.Error())
 		return
@@ -78,7 +78,7 @@ func (b *BlockParser) ActionBalanceCell(req FuncTransactionHandleReq) (resp Func
 		serviceType = dao.ServiceTypeTransaction
 	}
 
-	res, err := b.dasCore.Client().GetTransaction(b.ctx, req.Tx.Inputs[0].PreviousOutput.TxHash)
+	res, err := b.getTransaction(req.Tx.Inputs[0].PreviousOutput.TxHash)
 	if err != nil {
 		resp.Err = fmt.Errorf("GetTransaction err: %s", err.Error())
 		return
@@ -108,7 +108,7 @@ func (b *BlockParser) ActionBalanceCell(req FuncTransactionHandleReq) (resp Func
 		Outpoint:       common.OutPoint2String(req.TxHash, 0),
 		BlockTimestamp: req.BlockTimestamp,
 	}
-	if err := b.dbDao.CreateTransactionInfo(tx); err != nil {
+	if err := req.DbDao.CreateTransactionInfo(tx); err != nil {
 		log.Error("CreateTransactionInfo err:", err.Error(), toolib.JsonString(tx))
 		resp.Err = fmt.Errorf("WithdrawFromWallet err: %s", err.Error())
 		return
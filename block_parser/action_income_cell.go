@@ -70,7 +70,7 @@ func (b *BlockParser) ActionConsolidateIncome(req FuncTransactionHandleReq) (res
 		}
 	}
 
-	if err = b.dbDao.ConsolidateIncome(inputsOutpoints, incomeCellInfos, transactionInfos); err != nil {
+	if err = req.DbDao.ConsolidateIncome(inputsOutpoints, incomeCellInfos, transactionInfos); err != nil {
 		log.Error("ConsolidateIncome err: ", err.Error())
 		resp.Err = fmt.Errorf("ConsolidateIncome err: %s", err.Error())
 		return
@@ -68,7 +68,7 @@ func (b *BlockParser) ActionMakeOffer(req FuncTransactionHandleReq) (resp FuncTr
 
 	log.Info("ActionMakeOffer:", builder.Account)
 
-	if err = b.dbDao.MakeOffer(offerInfo, transactionInfo); err != nil {
+	if err = req.DbDao.MakeOffer(offerInfo, transactionInfo); err != nil {
 		resp.Err = fmt.Errorf("MakeOffer err: %s", err.Error())
 		return
 	}
@@ -136,7 +136,7 @@ func (b *BlockParser) ActionEditOffer(req FuncTransactionHandleReq) (resp FuncTr
 
 	log.Info("ActionEditOffer:", builder.Account)
 
-	if err = b.dbDao.EditOffer(oldOutpoint, offerInfo, transactionInfo); err != nil {
+	if err = req.DbDao.EditOffer(oldOutpoint, offerInfo, transactionInfo); err != nil {
 		resp.Err = fmt.Errorf("EditOffer err: %s", err.Error())
 		return
 	}
@@ -145,7 +145,7 @@ func (b *BlockParser) ActionEditOffer(req FuncTransactionHandleReq) (resp FuncTr
 }
 
 func (b *BlockParser) ActionCancelOffer(req FuncTransactionHandleReq) (resp FuncTransactionHandleResp) {
-	res, err := b.dasCore.Client().GetTransaction(b.ctx, req.Tx.Inputs[0].PreviousOutput.TxHash)
+	res, err := b.getTransaction(req.Tx.Inputs[0].PreviousOutput.TxHash)
 	if err != nil {
 		resp.Err = fmt.Errorf("GetTransaction err: %s", err.Error())
 		return
@@ -193,7 +193,7 @@ func (b *BlockParser) ActionCancelOffer(req FuncTransactionHandleReq) (resp Func
 		BlockTimestamp: req.BlockTimestamp,
 	}
 
-	if err = b.dbDao.CancelOffer(oldOutpoints, transactionInfo); err != nil {
+	if err = req.DbDao.CancelOffer(oldOutpoints, transactionInfo); err != nil {
 		resp.Err = fmt.Errorf("CancelOffer err: %s", err.Error())
 		return
 	}
@@ -202,7 +202,7 @@ func (b *BlockParser) ActionCancelOffer(req FuncTransactionHandleReq) (resp Func
 }
 
 func (b *BlockParser) ActionAcceptOffer(req FuncTransactionHandleReq) (resp FuncTransactionHandleResp) {
-	res, err := b.dasCore.Client().GetTransaction(b.ctx, req.Tx.Inputs[0].PreviousOutput.TxHash)
+	res, err := b.getTransaction(req.Tx.Inputs[0].PreviousOutput.TxHash)
 	if err != nil {
 		resp.Err = fmt.Errorf("GetTransaction err: %s", err.Error())
 		return
@@ -240,7 +240,7 @@ func (b *BlockParser) ActionAcceptOffer(req FuncTransactionHandleReq) (resp Func
 	}
 
 	// res account cell
-	resAccount, err := b.dasCore.Client().GetTransaction(b.ctx, req.Tx.Inputs[1].PreviousOutput.TxHash)
+	resAccount, err := b.getTransaction(req.Tx.Inputs[1].PreviousOutput.TxHash)
 	if err != nil {
 		resp.Err = fmt.Errorf("GetTransaction err: %s", err.Error())
 		return
@@ -357,7 +357,7 @@ func (b *BlockParser) ActionAcceptOffer(req FuncTransactionHandleReq) (resp Func
 
 	log.Info("ActionAcceptOffer:", buyerBuilder.AccountId, len(rebateList))
 
-	if err = b.dbDao.AcceptOffer(incomeCellInfos, accountInfo, offerOutpoint, tradeDealInfo, transactionInfoBuy, transactionInfoSale, rebateList, recordsInfos); err != nil {
+	if err = req.DbDao.AcceptOffer(incomeCellInfos, accountInfo, offerOutpoint, tradeDealInfo, transactionInfoBuy, transactionInfoSale, rebateList, recordsInfos); err != nil {
 		log.Error("AcceptOffer err:", err.Error(), toolib.JsonString(transactionInfoBuy), toolib.JsonString(transactionInfoSale))
 		resp.Err = fmt.Errorf("AcceptOffer err: %s", err.Error())
 		return
@@ -51,7 +51,7 @@ func (b *BlockParser) ActionPropose(req FuncTransactionHandleReq) (resp FuncTran
 		})
 	}
 
-	if err = b.dbDao.CreateTransactionInfoList(transactionInfos); err != nil {
+	if err = req.DbDao.CreateTransactionInfoList(transactionInfos); err != nil {
 		log.Error("CreateTransactionInfoList err:", err.Error(), req.TxHash, req.BlockNumber)
 		resp.Err = fmt.Errorf("CreateTransactionInfoList err: %s ", err.Error())
 		return
@@ -149,7 +149,7 @@ func (b *BlockParser) ActionConfirmProposal(req FuncTransactionHandleReq) (resp
 		})
 
 		if preAcc, ok := preMap[v.Account]; ok {
-			preTx, err := b.dasCore.Client().GetTransaction(b.ctx, req.Tx.Inputs[preAcc.Index].PreviousOutput.TxHash)
+			preTx, err := b.getTransaction(req.Tx.Inputs[preAcc.Index].PreviousOutput.TxHash)
 			if err != nil {
 				resp.Err = fmt.Errorf("GetTransaction err: %s", err.Error())
 				return
@@ -257,7 +257,7 @@ func (b *BlockParser) ActionConfirmProposal(req FuncTransactionHandleReq) (resp
 		}
 	}
 
-	if err = b.dbDao.ConfirmProposal(incomeCellInfos, accountInfos, transactionInfos, rebateInfos, records, recordAccountIds); err != nil {
+	if err = req.DbDao.ConfirmProposal(incomeCellInfos, accountInfos, transactionInfos, rebateInfos, records, recordAccountIds); err != nil {
 		log.Error("ConfirmProposal err:", err.Error(), req.TxHash, req.BlockNumber)
 		resp.Err = fmt.Errorf("ConfirmProposal err: %s ", err.Error())
 		return
@@ -49,7 +49,7 @@ func (b *BlockParser) ActionDeclareReverseRecord(req FuncTransactionHandleReq) (
 		BlockTimestamp: req.BlockTimestamp,
 	}
 
-	if err := b.dbDao.DeclareReverseRecord(reverseInfo, txInfo); err != nil {
+	if err := req.DbDao.DeclareReverseRecord(reverseInfo, txInfo); err != nil {
 		resp.Err = fmt.Errorf("DeclareReverseRecord err: %s", err.Error())
 		return
 	}
@@ -102,7 +102,7 @@ func (b *BlockParser) ActionRedeclareReverseRecord(req FuncTransactionHandleReq)
 		BlockTimestamp: req.BlockTimestamp,
 	}
 
-	if err := b.dbDao.RedeclareReverseRecord(lastOutpoint, reverseInfo, txInfo); err != nil {
+	if err := req.DbDao.RedeclareReverseRecord(lastOutpoint, reverseInfo, txInfo); err != nil {
 		resp.Err = fmt.Errorf("RedeclareReverseRecord err: %s", err.Error())
 		return
 	}
@@ -111,7 +111,7 @@ func (b *BlockParser) ActionRedeclareReverseRecord(req FuncTransactionHandleReq)
 }
 
 func (b *BlockParser) ActionRetractReverseRecord(req FuncTransactionHandleReq) (resp FuncTransactionHandleResp) {
-	res, err := b.dasCore.Client().GetTransaction(b.ctx, req.Tx.Inputs[0].PreviousOutput.TxHash)
+	res, err := b.getTransaction(req.Tx.Inputs[0].PreviousOutput.TxHash)
 	if err != nil {
 		resp.Err = fmt.Errorf("GetTransaction err: %s", err.Error())
 		return
@@ -148,7 +148,7 @@ func (b *BlockParser) ActionRetractReverseRecord(req FuncTransactionHandleReq) (
 		BlockTimestamp: req.BlockTimestamp,
 	}
 
-	if err := b.dbDao.RetractReverseRecord(listOutpoint, txInfo); err != nil {
+	if err := req.DbDao.RetractReverseRecord(listOutpoint, txInfo); err != nil {
 		resp.Err = fmt.Errorf("RetractReverseRecord err: %s", err.Error())
 		return
 	}
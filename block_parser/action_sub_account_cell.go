@@ -1,6 +1,7 @@
 package block_parser
 
 import (
+	"bytes"
 	"das_database/dao"
 	"fmt"
 	"github.com/dotbitHQ/das-lib/common"
@@ -52,7 +53,7 @@ func (b *BlockParser) ActionEnableSubAccount(req FuncTransactionHandleReq) (resp
 		BlockTimestamp: req.BlockTimestamp,
 	}
 
-	if err = b.dbDao.EnableSubAccount(accountInfo, transactionInfo); err != nil {
+	if err = req.DbDao.EnableSubAccount(accountInfo, transactionInfo); err != nil {
 		resp.Err = fmt.Errorf("EnableSubAccount err: %s", err.Error())
 		return
 	}
@@ -167,7 +168,7 @@ func (b *BlockParser) ActionCreateSubAccount(req FuncTransactionHandleReq) (resp
 		return
 	}
 
-	res, err := b.dasCore.Client().GetTransaction(b.ctx, req.Tx.Inputs[len(req.Tx.Inputs)-1].PreviousOutput.TxHash)
+	res, err := b.getTransaction(req.Tx.Inputs[len(req.Tx.Inputs)-1].PreviousOutput.TxHash)
 	if err != nil {
 		resp.Err = fmt.Errorf("GetTransaction err: %s", err.Error())
 		return
@@ -196,7 +197,7 @@ func (b *BlockParser) ActionCreateSubAccount(req FuncTransactionHandleReq) (resp
 		BlockTimestamp: req.BlockTimestamp,
 	}
 
-	if err = b.dbDao.CreateSubAccount(subAccountIds, accountInfos, smtInfos, transactionInfo, parentAccountInfo); err != nil {
+	if err = req.DbDao.CreateSubAccount(subAccountIds, accountInfos, smtInfos, transactionInfo, parentAccountInfo); err != nil {
 		resp.Err = fmt.Errorf("CreateSubAccount err: %s", err.Error())
 		return
 	}
@@ -204,6 +205,20 @@ func (b *BlockParser) ActionCreateSubAccount(req FuncTransactionHandleReq) (resp
 	return
 }
 
+// subAccountCellOutpoint returns the outpoint of the sub-account cell in the outputs of the tx
+func subAccountCellOutpoint(req FuncTransactionHandleReq) (string, error) {
+	contractSub, err := core.GetDasContractInfo(common.DASContractNameSubAccountCellType)
+	if err != nil {
+		return "", fmt.Errorf("GetDasContractInfo err: %s", err.Error())
+	}
+	for i, v := range req.Tx.Outputs {
+		if v.Type != nil && contractSub.IsSameTypeId(v.Type.CodeHash) {
+			return common.OutPoint2String(req.TxHash, uint(i)), nil
+		}
+	}
+	return "", fmt.Errorf("no sub-account cell in the outputs")
+}
+
 func (b *BlockParser) ActionEditSubAccount(req FuncTransactionHandleReq) (resp FuncTransactionHandleResp) {
 	if isCV, err := isCurrentVersionTx(req.Tx, common.DASContractNameSubAccountCellType); err != nil {
 		resp.Err = fmt.Errorf("isCurrentVersion err: %s", err.Error())
@@ -220,6 +235,11 @@ func (b *BlockParser) ActionEditSubAccount(req FuncTransactionHandleReq) (resp F
 		return
 	}
 
+	outpoint, err := subAccountCellOutpoint(req)
+	if err != nil {
+		resp.Err = fmt.Errorf("subAccountCellOutpoint err: %s", err.Error())
+		return
+	}
 	var index uint
 	for _, builder := range builderMap {
 		ownerHex, _, err := b.dasCore.Daf().ArgsToHex(builder.SubAccount.Lock.Args)
@@ -227,8 +247,6 @@ func (b *BlockParser) ActionEditSubAccount(req FuncTransactionHandleReq) (resp F
 			resp.Err = fmt.Errorf("ArgsToHex err: %s", err.Error())
 			return
 		}
-		outpoint := common.OutPoint2String(req.TxHash, 0)
-
 		accountInfo := dao.TableAccountInfo{
 			BlockNumber: req.BlockNumber,
 			Outpoint:    outpoint,
@@ -250,7 +268,7 @@ func (b *BlockParser) ActionEditSubAccount(req FuncTransactionHandleReq) (resp F
 			ChainType:      ownerHex.ChainType,
 			Address:        ownerHex.AddressHex,
 			Capacity:       0,
-			Outpoint:       common.OutPoint2String(outpoint, index),
+			Outpoint:       common.OutPoint2String(req.TxHash, index),
 			BlockTimestamp: req.BlockTimestamp,
 		}
 		index++
@@ -275,7 +293,7 @@ func (b *BlockParser) ActionEditSubAccount(req FuncTransactionHandleReq) (resp F
 			accountInfo.Manager = mHex.AddressHex
 			transactionInfo.ChainType = oHex.ChainType
 			transactionInfo.Address = oHex.AddressHex
-			if err = b.dbDao.EditOwnerSubAccount(accountInfo, smtInfo, transactionInfo); err != nil {
+			if err = req.DbDao.EditOwnerSubAccount(accountInfo, smtInfo, transactionInfo); err != nil {
 				resp.Err = fmt.Errorf("EditOwnerSubAccount err: %s", err.Error())
 			}
 		case common.EditKeyManager:
@@ -287,7 +305,7 @@ func (b *BlockParser) ActionEditSubAccount(req FuncTransactionHandleReq) (resp F
 			accountInfo.ManagerAlgorithmId = mHex.DasAlgorithmId
 			accountInfo.ManagerChainType = mHex.ChainType
 			accountInfo.Manager = mHex.AddressHex
-			if err = b.dbDao.EditManagerSubAccount(accountInfo, smtInfo, transactionInfo); err != nil {
+			if err = req.DbDao.EditManagerSubAccount(accountInfo, smtInfo, transactionInfo); err != nil {
 				resp.Err = fmt.Errorf("EditManagerSubAccount err: %s", err.Error())
 			}
 		case common.EditKeyRecords:
@@ -304,7 +322,7 @@ func (b *BlockParser) ActionEditSubAccount(req FuncTransactionHandleReq) (resp F
 					Ttl:             strconv.FormatUint(uint64(v.TTL), 10),
 				})
 			}
-			if err = b.dbDao.EditRecordsSubAccount(accountInfo, smtInfo, transactionInfo, recordsInfos); err != nil {
+			if err = req.DbDao.EditRecordsSubAccount(accountInfo, smtInfo, transactionInfo, recordsInfos); err != nil {
 				resp.Err = fmt.Errorf("EditRecordsSubAccount err: %s", err.Error())
 				return
 			}
@@ -349,6 +367,11 @@ func (b *BlockParser) ActionRenewSubAccount(req FuncTransactionHandleReq) (resp
 	var accountInfos []dao.TableAccountInfo
 	var smtInfos []dao.TableSmtInfo
 	var transactionInfos []dao.TableTransactionInfo
+	outpoint, err := subAccountCellOutpoint(req)
+	if err != nil {
+		resp.Err = fmt.Errorf("subAccountCellOutpoint err: %s", err.Error())
+		return
+	}
 	var index uint
 	for _, builder := range builderMap {
 		oHex, _, err := b.dasCore.Daf().ArgsToHex(builder.SubAccount.Lock.Args)
@@ -356,8 +379,6 @@ func (b *BlockParser) ActionRenewSubAccount(req FuncTransactionHandleReq) (resp
 			resp.Err = fmt.Errorf("ArgsToHex err: %s", err.Error())
 			return
 		}
-		outpoint := common.OutPoint2String(req.TxHash, 0)
-
 		accountInfo := dao.TableAccountInfo{
 			BlockNumber: req.BlockNumber,
 			Outpoint:    outpoint,
@@ -378,7 +399,7 @@ func (b *BlockParser) ActionRenewSubAccount(req FuncTransactionHandleReq) (resp
 			ServiceType:    dao.ServiceTypeRegister,
 			ChainType:      oHex.ChainType,
 			Address:        oHex.AddressHex,
-			Outpoint:       common.OutPoint2String(outpoint, index),
+			Outpoint:       common.OutPoint2String(req.TxHash, index),
 			BlockTimestamp: req.BlockTimestamp,
 		}
 		index++
@@ -398,7 +419,7 @@ func (b *BlockParser) ActionRenewSubAccount(req FuncTransactionHandleReq) (resp
 		}
 	}
 
-	if err = b.dbDao.RenewSubAccount(accountInfos, smtInfos, transactionInfos); err != nil {
+	if err = req.DbDao.RenewSubAccount(accountInfos, smtInfos, transactionInfos); err != nil {
 		resp.Err = fmt.Errorf("RenewSubAccount err: %s", err.Error())
 		return
 	}
@@ -441,8 +462,6 @@ func (b *BlockParser) ActionRecycleSubAccount(req FuncTransactionHandleReq) (res
 			resp.Err = fmt.Errorf("ArgsToHex err: %s", err.Error())
 			return
 		}
-		outpoint := common.OutPoint2String(req.TxHash, 0)
-
 		accountIds = append(accountIds, builder.SubAccount.AccountId)
 		transactionInfos = append(transactionInfos, dao.TableTransactionInfo{
 			BlockNumber:    req.BlockNumber,
@@ -453,13 +472,13 @@ func (b *BlockParser) ActionRecycleSubAccount(req FuncTransactionHandleReq) (res
 			ChainType:      oHex.ChainType,
 			Address:        oHex.AddressHex,
 			Capacity:       req.Tx.Outputs[0].Capacity,
-			Outpoint:       common.OutPoint2String(outpoint, index),
+			Outpoint:       common.OutPoint2String(req.TxHash, index),
 			BlockTimestamp: req.BlockTimestamp,
 		})
 		index++
 	}
 
-	if err = b.dbDao.RecycleSubAccount(accountIds, transactionInfos); err != nil {
+	if err = req.DbDao.RecycleSubAccount(accountIds, transactionInfos); err != nil {
 		resp.Err = fmt.Errorf("RecycleSubAccount err: %s", err.Error())
 		return
 	}
@@ -468,7 +487,82 @@ func (b *BlockParser) ActionRecycleSubAccount(req FuncTransactionHandleReq) (res
 }*/
 
 func (b *BlockParser) ActionSubAccountCrossChain(req FuncTransactionHandleReq) (resp FuncTransactionHandleResp) {
+	if isCV, err := isCurrentVersionTx(req.Tx, common.DASContractNameSubAccountCellType); err != nil {
+		resp.Err = fmt.Errorf("isCurrentVersion err: %s", err.Error())
+		return
+	} else if !isCV {
+		log.Warn("not current version sub account cross chain tx")
+		return
+	}
 	log.Info("ActionSubAccountCrossChain:", req.BlockNumber, req.TxHash, req.Action)
+
+	builderMap, err := witness.SubAccountBuilderMapFromTx(req.Tx)
+	if err != nil {
+		resp.Err = fmt.Errorf("SubAccountBuilderMapFromTx err: %s", err.Error())
+		return
+	}
+
+	status := common.AccountStatusOnCross
+	if req.Action == common.DasActionUnlockSubAccountForCrossChain {
+		status = common.AccountStatusNormal
+	}
+
+	outpoint, err := subAccountCellOutpoint(req)
+	if err != nil {
+		resp.Err = fmt.Errorf("subAccountCellOutpoint err: %s", err.Error())
+		return
+	}
+	var index uint
+	for _, builder := range builderMap {
+		ownerHex, managerHex, err := b.dasCore.Daf().ArgsToHex(builder.CurrentSubAccount.Lock.Args)
+		if err != nil {
+			resp.Err = fmt.Errorf("ArgsToHex err: %s", err.Error())
+			return
+		}
+		// the lock of the sub-account is only replaced when it is unlocked to a new owner
+		isTrans := req.Action == common.DasActionUnlockSubAccountForCrossChain &&
+			!bytes.EqualFold(builder.SubAccount.Lock.Args, builder.CurrentSubAccount.Lock.Args)
+		builder.CurrentSubAccount.Status = status
+		accountInfo := dao.TableAccountInfo{
+			BlockNumber:        req.BlockNumber,
+			Outpoint:           outpoint,
+			AccountId:          builder.SubAccount.AccountId,
+			OwnerChainType:     ownerHex.ChainType,
+			Owner:              ownerHex.AddressHex,
+			OwnerAlgorithmId:   ownerHex.DasAlgorithmId,
+			ManagerChainType:   managerHex.ChainType,
+			Manager:            managerHex.AddressHex,
+			ManagerAlgorithmId: managerHex.DasAlgorithmId,
+			Status:             status,
+			Nonce:              builder.CurrentSubAccount.Nonce,
+		}
+		smtInfo := dao.TableSmtInfo{
+			BlockNumber:  req.BlockNumber,
+			Outpoint:     outpoint,
+			AccountId:    builder.SubAccount.AccountId,
+			LeafDataHash: common.Bytes2Hex(builder.CurrentSubAccount.ToH256()),
+		}
+		transactionInfo := dao.TableTransactionInfo{
+			BlockNumber:    req.BlockNumber,
+			AccountId:      builder.SubAccount.AccountId,
+			Account:        builder.Account,
+			Action:         req.Action,
+			ServiceType:    dao.ServiceTypeRegister,
+			ChainType:      ownerHex.ChainType,
+			Address:        ownerHex.AddressHex,
+			Capacity:       0,
+			Outpoint:       common.OutPoint2String(req.TxHash, index),
+			BlockTimestamp: req.BlockTimestamp,
+		}
+		index++
+
+		if err = req.DbDao.SubAccountCrossChain(accountInfo, smtInfo, transactionInfo, isTrans); err != nil {
+			log.Error("SubAccountCrossChain err:", err.Error(), req.TxHash, req.BlockNumber)
+			resp.Err = fmt.Errorf("SubAccountCrossChain err: %s ", err.Error())
+			return
+		}
+	}
+
 	return
 }
 
@@ -514,7 +608,7 @@ func (b *BlockParser) ActionConfigSubAccountCreatingScript(req FuncTransactionHa
 		BlockTimestamp: req.BlockTimestamp,
 	}
 
-	if err = b.dbDao.UpdateCustomScript(cs, accountCellOutpoint, transactionInfo); err != nil {
+	if err = req.DbDao.UpdateCustomScript(cs, accountCellOutpoint, transactionInfo); err != nil {
 		resp.Err = fmt.Errorf("UpdateAccountOutpoint err: %s", err.Error())
 	}
 
@@ -576,7 +670,7 @@ func (b *BlockParser) ActionCollectSubAccountProfit(req FuncTransactionHandleReq
 		})
 	}
 
-	if err := b.dbDao.CreateTxs(txs); err != nil {
+	if err := req.DbDao.CreateTxs(txs); err != nil {
 		resp.Err = fmt.Errorf("CreateTxs err: %s", err.Error())
 		return
 	}
@@ -2,9 +2,9 @@ package block_parser
 
 import (
 	"context"
-	"das_database/config"
 	"das_database/dao"
-	"das_database/notify"
+	"das_database/das_env"
+	"das_database/metrics"
 	"fmt"
 	"github.com/dotbitHQ/das-lib/common"
 	"github.com/dotbitHQ/das-lib/core"
@@ -17,42 +17,79 @@ import (
 )
 
 var log = mylog.NewLogger("block_parser", mylog.LevelDebug)
-var IsLatestBlockNumber bool
 
 type BlockParser struct {
+	network              string // label of the metrics
 	dasCore              *core.DasCore
+	dasEnv               *das_env.Env
+	blockSource          BlockSource
 	mapTransactionHandle map[common.DasAction]FuncTransactionHandle
 	currentBlockNumber   uint64
 	dbDao                *dao.DbDao
 	concurrencyNum       uint64
+	fetchWorkerNum       uint64
 	confirmNum           uint64
+	pollInterval         time.Duration
+	tipSubscriber        *tipSubscriber
+	provisional          bool
+	provisionalKey       string // cursor and tip hash of the last provisional build
 	ctx                  context.Context
 	wg                   *sync.WaitGroup
 
-	errCountHandle int
+	prefetcher      *blockPrefetcher // next blocks of parserConcurrencyMode
+	txCache         txCache          // prefetched previous transactions
+	state           parserState
+	retry           retryState
+	postCommitHooks []FuncPostCommitHook
+	nonDasTxs       map[string]struct{} // txs of the pool seen not to be das txs, see trackPendingTx
+	mapEventDef     map[common.DasAction]eventDef
+	repairRunning   int32 // a single repair at a time, see RepairAccount
 }
 
 type ParamsBlockParser struct {
+	Network            string // optional, label of the metrics
 	DasCore            *core.DasCore
+	DasEnv             *das_env.Env // optional, the das-lib maps of the network, for a process parsing several networks
 	CurrentBlockNumber uint64
 	DbDao              *dao.DbDao
 	ConcurrencyNum     uint64
+	FetchWorkerNum     uint64
 	ConfirmNum         uint64
+	BlockSource        BlockSource   // optional, the ckb node of DasCore by default
+	TipSubscribeUrl    string        // optional, ws:// or tcp:// subscription of the node waking the parser on new tips
+	PollInterval       time.Duration // between the polls of the tip once caught up, defaultPollInterval if 0
+	Provisional        bool          // parse the blocks not yet confirmed into t_provisional_change once caught up
 	Ctx                context.Context
 	Wg                 *sync.WaitGroup
 }
 
 func NewBlockParser(p ParamsBlockParser) (*BlockParser, error) {
 	bp := BlockParser{
+		network:            p.Network,
 		dasCore:            p.DasCore,
+		dasEnv:             p.DasEnv,
+		blockSource:        p.BlockSource,
 		currentBlockNumber: p.CurrentBlockNumber,
 		dbDao:              p.DbDao,
 		concurrencyNum:     p.ConcurrencyNum,
+		fetchWorkerNum:     p.FetchWorkerNum,
 		confirmNum:         p.ConfirmNum,
+		pollInterval:       p.PollInterval,
+		provisional:        p.Provisional,
 		ctx:                p.Ctx,
 		wg:                 p.Wg,
 	}
+	if bp.blockSource == nil {
+		bp.blockSource = NewRpcBlockSource(p.DasCore.Client())
+	}
+	if p.TipSubscribeUrl != "" {
+		bp.tipSubscriber = newTipSubscriber(p.TipSubscribeUrl)
+	}
 	bp.registerTransactionHandle()
+	bp.mapEventDef = make(map[common.DasAction]eventDef, len(mapEventDef))
+	for k, v := range mapEventDef {
+		bp.mapEventDef[k] = v
+	}
 	if err := bp.initCurrentBlockNumber(); err != nil {
 		return nil, fmt.Errorf("initCurrentBlockNumber err: %s", err.Error())
 	}
@@ -75,36 +112,78 @@ func (b *BlockParser) initCurrentBlockNumber() error {
 
 func (b *BlockParser) RunParser() {
 	atomic.AddUint64(&b.currentBlockNumber, 1)
+	if b.tipSubscriber != nil {
+		b.tipSubscriber.run(b.ctx, b.wg)
+	}
+	// the provisional changes of the last run may be long confirmed
+	if b.provisional {
+		if err := b.dbDao.ReplaceProvisionalChanges(nil); err != nil {
+			log.Error("ReplaceProvisionalChanges err:", err.Error())
+		}
+	}
 	b.wg.Add(1)
 	go func() {
+		halted := false
 		for {
 			select {
 			default:
+				// halted by the parser policy, wait for Resume
+				if b.state.isHalted() {
+					halted = true
+					time.Sleep(time.Second)
+					continue
+				} else if halted {
+					halted = false
+					b.retry.reset()
+				}
 				// get the new height and compare with current height
-				latestBlockNumber, err := b.dasCore.Client().GetTipBlockNumber(b.ctx)
+				latestBlockNumber, err := b.getTipBlockNumber()
 				if err != nil {
 					log.Error("get latest block number err:", err.Error())
 				} else {
+					metrics.SetBlockNumber(b.network, b.currentBlockNumber, latestBlockNumber)
 					// async
 					if b.concurrencyNum > 1 && b.currentBlockNumber < (latestBlockNumber-b.confirmNum-b.concurrencyNum) {
+						b.state.setTip(b.currentBlockNumber, latestBlockNumber, false)
 						nowTime := time.Now()
-						if err = b.parserConcurrencyMode(); err != nil {
+						if err = b.dasEnv.Run(func() error { return b.parserConcurrencyMode(latestBlockNumber) }); err != nil {
 							log.Error("parserConcurrencyMode err:", err.Error(), b.currentBlockNumber)
 						}
 						log.Warn("parserConcurrencyMode time:", time.Since(nowTime).Seconds())
 					} else if b.currentBlockNumber < (latestBlockNumber - b.confirmNum) { // check rollback
+						b.state.setTip(b.currentBlockNumber, latestBlockNumber, false)
 						nowTime := time.Now()
-						if err = b.parserSubMode(); err != nil {
+						if err = b.dasEnv.Run(b.parserSubMode); err != nil {
 							log.Error("parserSubMode err:", err.Error(), b.currentBlockNumber)
 						}
 						log.Warn("parserSubMode time:", time.Since(nowTime).Seconds())
 					} else {
-						log.Info("RunParser:", IsLatestBlockNumber, b.currentBlockNumber, latestBlockNumber)
-						IsLatestBlockNumber = true
-						time.Sleep(time.Second * 10)
+						log.Info("RunParser:", b.currentBlockNumber, latestBlockNumber)
+						b.state.setTip(b.currentBlockNumber, latestBlockNumber, true)
+						_ = b.dasEnv.Run(func() error {
+							b.reprocessDeadLetters()
+							if b.provisional {
+								if err := b.parseProvisionalBlocks(latestBlockNumber); err != nil {
+									log.Error("parseProvisionalBlocks err:", err.Error())
+								}
+							}
+							return nil
+						})
+						b.waitNewTip()
 					}
-					time.Sleep(time.Millisecond * 300)
 				}
+				b.state.setErr(err)
+				if err != nil {
+					// retry the same block with backoff
+					b.retry.failBlock(b.currentBlockNumber)
+					b.checkStuck()
+					if !b.waitRetry() {
+						continue
+					}
+				} else {
+					b.retry.reset()
+				}
+				time.Sleep(time.Millisecond * 300)
 			case <-b.ctx.Done():
 				b.wg.Done()
 				return
@@ -116,7 +195,7 @@ func (b *BlockParser) RunParser() {
 // subscribe mode
 func (b *BlockParser) parserSubMode() error {
 	log.Info("parserSubMode:", b.currentBlockNumber)
-	block, err := b.dasCore.Client().GetBlockByNumber(b.ctx, b.currentBlockNumber)
+	block, err := b.getBlockByNumber(b.ctx, b.currentBlockNumber)
 	if err != nil {
 		return fmt.Errorf("GetBlockByNumber err: %s", err.Error())
 	} else {
@@ -128,18 +207,22 @@ func (b *BlockParser) parserSubMode() error {
 			return fmt.Errorf("checkFork err: %s", err.Error())
 		} else if fork {
 			log.Warn("CheckFork is true:", b.currentBlockNumber, blockHash, parentHash)
+			metrics.ForksTotal.WithLabelValues(b.network).Inc()
+			// revert the orphaned block before re-parsing the canonical chain
+			if err = b.dbDao.RollbackBlock(b.currentBlockNumber - 1); err != nil {
+				return fmt.Errorf("RollbackBlock err: %s", err.Error())
+			}
 			atomic.AddUint64(&b.currentBlockNumber, ^uint64(0))
 		} else if err = b.parsingBlockData(block); err != nil {
 			return fmt.Errorf("parsingBlockData err: %s", err.Error())
 		} else {
-			if err = b.dbDao.CreateBlockInfo(b.currentBlockNumber, blockHash, parentHash); err != nil {
-				return fmt.Errorf("CreateBlockInfo err: %s", err.Error())
-			} else {
-				atomic.AddUint64(&b.currentBlockNumber, 1)
-			}
+			atomic.AddUint64(&b.currentBlockNumber, 1)
 			if err = b.dbDao.DeleteBlockInfo(b.currentBlockNumber - 20); err != nil {
 				return fmt.Errorf("DeleteBlockInfo err: %s", err.Error())
 			}
+			if err = b.dbDao.DeleteBlockUndoInfo(b.currentBlockNumber - 20); err != nil {
+				return fmt.Errorf("DeleteBlockUndoInfo err: %s", err.Error())
+			}
 		}
 	}
 	return nil
@@ -160,70 +243,86 @@ func (b *BlockParser) checkFork(parentHash string) (bool, error) {
 	return false, nil
 }
 
+// parsingBlockData applies the handlers of all txs in the block and the block cursor in one db transaction,
+// so a block is either fully indexed or not at all
 func (b *BlockParser) parsingBlockData(block *types.Block) error {
-	for _, tx := range block.Transactions {
-		txHash := tx.Hash.Hex()
-		blockNumber := block.Header.Number
-		blockTimestamp := block.Header.Timestamp
-		log.Info("parsingBlockData txHash:", txHash)
-
-		if builder, err := witness.ActionDataBuilderFromTx(tx); err != nil {
-			log.Warn("ActionDataBuilderFromTx err:", err.Error())
-		} else {
-			if handle, ok := b.mapTransactionHandle[builder.Action]; ok {
-				// transaction parse by action
-				resp := handle(FuncTransactionHandleReq{
-					DbDao:          b.dbDao,
-					Tx:             tx,
-					TxHash:         txHash,
-					BlockNumber:    blockNumber,
-					BlockTimestamp: blockTimestamp,
-					Action:         builder.Action,
-				})
-				if resp.Err != nil {
-					log.Error("action handle resp:", builder.Action, blockNumber, txHash, resp.Err.Error())
-					b.errCountHandle++
-					if b.errCountHandle < 100 {
-						// notify
-						msg := "> Transaction hash：%s\n> Action：%s\n> Timestamp：%s\n> Error message：%s"
-						msg = fmt.Sprintf(msg, txHash, builder.Action, time.Now().Format("2006-01-02 15:04:05"), resp.Err.Error())
-						err = notify.SendLarkTextNotify(config.Cfg.Notice.WebhookLarkErr, "DasDatabase BlockParser", msg)
-						if err != nil {
-							log.Error("SendLarkTextNotify err:", err.Error())
-						}
+	blockNumber := block.Header.Number
+	blockTimestamp := block.Header.Timestamp
+	// every write of the handlers is journaled under the block number, see dao.RollbackBlock
+	var txs []PostCommitTx
+	err := b.dbDao.WithBlockNumber(blockNumber).Transaction(func(blockDao *dao.DbDao) error {
+		for _, tx := range block.Transactions {
+			txHash := tx.Hash.Hex()
+			log.Info("parsingBlockData txHash:", txHash)
+
+			if builder, err := witness.ActionDataBuilderFromTx(tx); err != nil {
+				log.Warn("ActionDataBuilderFromTx err:", err.Error())
+			} else {
+				if handle, ok := b.mapTransactionHandle[builder.Action]; ok {
+					// transaction parse by action
+					handled, err := b.handleTransaction(blockDao, handle, FuncTransactionHandleReq{
+						Tx:             tx,
+						TxHash:         txHash,
+						BlockNumber:    blockNumber,
+						BlockTimestamp: blockTimestamp,
+						Action:         builder.Action,
+					})
+					if err != nil {
+						return err
+					} else if handled != nil {
+						txs = append(txs, *handled)
 					}
-					return resp.Err
 				}
 			}
 		}
+
+		if err := blockDao.CreateBlockInfo(blockNumber, block.Header.Hash.Hex(), block.Header.ParentHash.Hex()); err != nil {
+			return fmt.Errorf("CreateBlockInfo err: %s", err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	b.errCountHandle = 0
+	b.txCache.evict(blockNumber)
+	metrics.BlocksTotal.WithLabelValues(b.network).Inc()
+	b.state.setBlockParsed()
+	b.runPostCommitHooks(PostCommitReq{
+		BlockNumber:    blockNumber,
+		BlockHash:      block.Header.Hash.Hex(),
+		BlockTimestamp: blockTimestamp,
+		Txs:            txs,
+	})
 	return nil
 }
 
-func (b *BlockParser) parserConcurrencyMode() error {
+// parserConcurrencyMode parses the next concurrencyNum blocks, taken from the prefetcher
+// which keeps fetching up to confirm_num blocks below the tip
+func (b *BlockParser) parserConcurrencyMode(latestBlockNumber uint64) error {
 	log.Info("parserConcurrencyMode:", b.currentBlockNumber, b.concurrencyNum)
+	if b.prefetcher == nil {
+		b.prefetcher = newBlockPrefetcher(b, b.concurrencyNum)
+	}
 	for i := uint64(0); i < b.concurrencyNum; i++ {
-		block, err := b.dasCore.Client().GetBlockByNumber(b.ctx, b.currentBlockNumber)
+		block, err := b.prefetcher.take(b.currentBlockNumber, latestBlockNumber-b.confirmNum)
 		if err != nil {
-			return fmt.Errorf("GetBlockByNumber err: %s [%d]", err.Error(), b.currentBlockNumber)
+			return err
 		}
 		blockHash := block.Header.Hash.Hex()
 		parentHash := block.Header.ParentHash.Hex()
 		log.Info("parserConcurrencyMode:", b.currentBlockNumber, blockHash, parentHash)
 
-		if err = b.parsingBlockData(block); err != nil {
+		if err := b.parsingBlockData(block); err != nil {
+			b.prefetcher.reset(b.currentBlockNumber)
 			return fmt.Errorf("parsingBlockData err: %s", err.Error())
-		} else {
-			if err = b.dbDao.CreateBlockInfo(b.currentBlockNumber, blockHash, parentHash); err != nil {
-				return fmt.Errorf("CreateBlockInfo err: %s", err.Error())
-			} else {
-				atomic.AddUint64(&b.currentBlockNumber, 1)
-			}
 		}
+		atomic.AddUint64(&b.currentBlockNumber, 1)
 	}
 	if err := b.dbDao.DeleteBlockInfo(b.currentBlockNumber - 20); err != nil {
 		return fmt.Errorf("DeleteBlockInfo err: %s", err.Error())
 	}
+	if err := b.dbDao.DeleteBlockUndoInfo(b.currentBlockNumber - 20); err != nil {
+		return fmt.Errorf("DeleteBlockUndoInfo err: %s", err.Error())
+	}
 	return nil
 }
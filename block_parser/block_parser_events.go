@@ -0,0 +1,113 @@
+package block_parser
+
+import (
+	"das_database/dao"
+	"encoding/json"
+	"fmt"
+	"github.com/dotbitHQ/das-lib/common"
+	"reflect"
+)
+
+// eventDef is the domain event of an action, one event is emitted per account written to its table by the tx
+type eventDef struct {
+	EventType string
+	Table     string
+}
+
+// mapEventDef is the built-in events, copied by every BlockParser
+var mapEventDef = map[common.DasAction]eventDef{
+	common.DasActionConfirmProposal:            {"AccountRegistered", dao.TableNameAccountInfo},
+	common.DasActionEditRecords:                {"RecordsEdited", dao.TableNameAccountInfo},
+	common.DasActionTransferAccount:            {"OwnerTransferred", dao.TableNameAccountInfo},
+	common.DasActionEditManager:                {"ManagerChanged", dao.TableNameAccountInfo},
+	common.DasActionRenewAccount:               {"AccountRenewed", dao.TableNameAccountInfo},
+	common.DasActionRecycleExpiredAccount:      {"AccountRecycled", dao.TableNameAccountInfo},
+	common.DasActionForceRecoverAccountStatus:  {"AccountStatusRecovered", dao.TableNameAccountInfo},
+	common.DasActionLockAccountForCrossChain:   {"AccountCrossChainLocked", dao.TableNameAccountInfo},
+	common.DasActionUnlockAccountForCrossChain: {"AccountCrossChainUnlocked", dao.TableNameAccountInfo},
+
+	common.DasActionStartAccountSale:  {"ListingStarted", dao.TableNameTradeInfo},
+	common.DasActionEditAccountSale:   {"ListingEdited", dao.TableNameTradeInfo},
+	common.DasActionCancelAccountSale: {"ListingCancelled", dao.TableNameTradeInfo},
+	common.DasActionBuyAccount:        {"AccountSold", dao.TableNameTradeDealInfo},
+
+	common.DasActionMakeOffer:   {"OfferMade", dao.TableNameOfferInfo},
+	common.DasActionEditOffer:   {"OfferEdited", dao.TableNameOfferInfo},
+	common.DasActionCancelOffer: {"OfferCancelled", dao.TableNameOfferInfo},
+	common.DasActionAcceptOffer: {"OfferAccepted", dao.TableNameTradeDealInfo},
+
+	common.DasActionDeclareReverseRecord:   {"ReverseRecordDeclared", dao.TableNameReverseInfo},
+	common.DasActionRedeclareReverseRecord: {"ReverseRecordRedeclared", dao.TableNameReverseInfo},
+	common.DasActionRetractReverseRecord:   {"ReverseRecordRetracted", dao.TableNameReverseInfo},
+
+	common.DasActionEnableSubAccount:              {"SubAccountEnabled", dao.TableNameAccountInfo},
+	common.DasActionCreateSubAccount:              {"SubAccountCreated", dao.TableNameAccountInfo},
+	common.DasActionEditSubAccount:                {"SubAccountEdited", dao.TableNameAccountInfo},
+	common.DasActionRenewSubAccount:               {"SubAccountRenewed", dao.TableNameAccountInfo},
+	common.DasActionRecycleSubAccount:             {"SubAccountRecycled", dao.TableNameAccountInfo},
+	common.DasActionLockSubAccountForCrossChain:   {"SubAccountCrossChainLocked", dao.TableNameAccountInfo},
+	common.DasActionUnlockSubAccountForCrossChain: {"SubAccountCrossChainUnlocked", dao.TableNameAccountInfo},
+}
+
+// RegisterEventType emits the event for the accounts written to table by the txs of the action on the network of the parser,
+// replacing the built-in event if any. It must be called before RunParser.
+func (b *BlockParser) RegisterEventType(action common.DasAction, eventType, table string) {
+	b.mapEventDef[action] = eventDef{EventType: eventType, Table: table}
+}
+
+// the history tables repeat the rows of the other tables, they are left out of the payload
+var eventExcludedTables = map[string]bool{
+	dao.TableNameAccountHistory: true,
+	dao.TableNameRecordsHistory: true,
+}
+
+// buildEventList returns the outbox events of a handled tx, the payload of an event is the rows written for its account
+func buildEventList(defs map[common.DasAction]eventDef, req FuncTransactionHandleReq, rows []dao.RowChange) ([]dao.TableEventOutbox, error) {
+	def, ok := defs[req.Action]
+	if !ok {
+		return nil, nil
+	}
+	var list []dao.TableEventOutbox
+	index := make(map[string]int)
+	payloads := make(map[string][]dao.RowChange)
+	for _, v := range rows {
+		accountId := rowField(v.Row, "AccountId")
+		if accountId == "" || eventExcludedTables[v.Table] {
+			continue
+		}
+		payloads[accountId] = append(payloads[accountId], v)
+		if _, ok := index[accountId]; ok || v.Table != def.Table {
+			continue
+		}
+		index[accountId] = len(list)
+		list = append(list, dao.TableEventOutbox{
+			EventType:      def.EventType,
+			BlockNumber:    req.BlockNumber,
+			BlockTimestamp: req.BlockTimestamp,
+			TxHash:         req.TxHash,
+			Action:         req.Action,
+			AccountId:      accountId,
+			Account:        rowField(v.Row, "Account"),
+		})
+	}
+	for accountId, i := range index {
+		payload, err := json.Marshal(payloads[accountId])
+		if err != nil {
+			return nil, fmt.Errorf("json.Marshal err: %s", err.Error())
+		}
+		list[i].Payload = string(payload)
+	}
+	return list, nil
+}
+
+func rowField(row interface{}, name string) string {
+	v := reflect.Indirect(reflect.ValueOf(row))
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	f := v.FieldByName(name)
+	if !f.IsValid() || f.Kind() != reflect.String {
+		return ""
+	}
+	return f.String()
+}
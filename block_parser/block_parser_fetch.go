@@ -0,0 +1,231 @@
+package block_parser
+
+import (
+	"context"
+	"das_database/metrics"
+	"fmt"
+	"github.com/dotbitHQ/das-lib/witness"
+	"github.com/nervosnetwork/ckb-sdk-go/types"
+	"sync"
+	"time"
+)
+
+const defaultFetchWorkerNum = 10
+
+type fetchResult struct {
+	block *types.Block
+	err   error
+}
+
+type fetchJob struct {
+	ctx         context.Context
+	blockNumber uint64
+	result      chan fetchResult
+}
+
+// blockPrefetcher keeps the next window blocks after the cursor fetched by a long-lived worker pool,
+// every block taken by the committer schedules the next one, so the workers never wait for a batch to finish
+type blockPrefetcher struct {
+	b       *BlockParser
+	window  uint64
+	jobs    chan fetchJob
+	ctx     context.Context
+	cancel  context.CancelFunc
+	results map[uint64]chan fetchResult
+	next    uint64 // next block the committer takes
+	end     uint64 // next block to schedule
+}
+
+func newBlockPrefetcher(b *BlockParser, window uint64) *blockPrefetcher {
+	workerNum := b.fetchWorkerNum
+	if workerNum == 0 {
+		workerNum = defaultFetchWorkerNum
+	}
+	p := blockPrefetcher{
+		b:       b,
+		window:  window,
+		jobs:    make(chan fetchJob, window),
+		results: make(map[uint64]chan fetchResult),
+	}
+	p.ctx, p.cancel = context.WithCancel(b.ctx)
+	for w := uint64(0); w < workerNum; w++ {
+		go p.work()
+	}
+	return &p
+}
+
+func (p *blockPrefetcher) work() {
+	for {
+		select {
+		case job := <-p.jobs:
+			if job.ctx.Err() != nil {
+				job.result <- fetchResult{err: job.ctx.Err()}
+				continue
+			}
+			block, err := p.b.getBlockByNumber(job.ctx, job.blockNumber)
+			if err != nil {
+				job.result <- fetchResult{err: fmt.Errorf("GetBlockByNumber err: %s [%d]", err.Error(), job.blockNumber)}
+				continue
+			}
+			p.b.prefetchPreviousTransactions(job.ctx, block)
+			job.result <- fetchResult{block: block}
+		case <-p.b.ctx.Done():
+			return
+		}
+	}
+}
+
+// take returns the block blockNumber, scheduling the blocks of the window below limit.
+// A block other than the one after the last taken restarts the window from it.
+func (p *blockPrefetcher) take(blockNumber, limit uint64) (*types.Block, error) {
+	if blockNumber != p.next {
+		p.reset(blockNumber)
+	}
+	for p.end < p.next+p.window && p.end < limit {
+		result := make(chan fetchResult, 1)
+		p.results[p.end] = result
+		select {
+		case p.jobs <- fetchJob{ctx: p.ctx, blockNumber: p.end, result: result}:
+		case <-p.ctx.Done():
+			return nil, p.ctx.Err()
+		}
+		p.end++
+	}
+	result, ok := p.results[blockNumber]
+	if !ok {
+		return nil, fmt.Errorf("block %d not scheduled, limit %d", blockNumber, limit)
+	}
+	// the workers are gone once the parser is stopped
+	var res fetchResult
+	select {
+	case res = <-result:
+	case <-p.ctx.Done():
+		return nil, p.ctx.Err()
+	}
+	delete(p.results, blockNumber)
+	if res.err != nil {
+		p.reset(blockNumber)
+		return nil, res.err
+	}
+	p.next = blockNumber + 1
+	return res.block, nil
+}
+
+// reset drops the scheduled blocks, the fetches in flight are cancelled
+func (p *blockPrefetcher) reset(blockNumber uint64) {
+	p.cancel()
+	for len(p.jobs) > 0 {
+		<-p.jobs
+	}
+	p.ctx, p.cancel = context.WithCancel(p.b.ctx)
+	p.results = make(map[uint64]chan fetchResult)
+	p.next, p.end = blockNumber, blockNumber
+}
+
+type txCacheEntry struct {
+	res         *types.TransactionWithStatus
+	blockNumber uint64 // last block referencing the tx
+}
+
+// txCache holds the previous transactions of the prefetched blocks until the last block referencing them is committed
+type txCache struct {
+	lock    sync.Mutex
+	entries map[string]*txCacheEntry
+}
+
+func (c *txCache) load(hash string, blockNumber uint64) (*types.TransactionWithStatus, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, ok := c.entries[hash]
+	if !ok {
+		return nil, false
+	}
+	if entry.blockNumber < blockNumber {
+		entry.blockNumber = blockNumber
+	}
+	return entry.res, true
+}
+
+func (c *txCache) store(hash string, blockNumber uint64, res *types.TransactionWithStatus) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]*txCacheEntry)
+	}
+	if entry, ok := c.entries[hash]; ok && entry.blockNumber > blockNumber {
+		blockNumber = entry.blockNumber
+	}
+	c.entries[hash] = &txCacheEntry{res: res, blockNumber: blockNumber}
+}
+
+// evict drops the transactions no block after blockNumber references
+func (c *txCache) evict(blockNumber uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for k, v := range c.entries {
+		if v.blockNumber <= blockNumber {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// prefetchPreviousTransactions caches the input transactions of the das txs in the block,
+// the handlers look them up through getTransaction
+func (b *BlockParser) prefetchPreviousTransactions(ctx context.Context, block *types.Block) {
+	for _, tx := range block.Transactions {
+		builder, err := witness.ActionDataBuilderFromTx(tx)
+		if err != nil {
+			continue
+		}
+		if _, ok := b.mapTransactionHandle[builder.Action]; !ok {
+			continue
+		}
+		for _, input := range tx.Inputs {
+			hash := input.PreviousOutput.TxHash
+			if _, ok := b.txCache.load(hash.Hex(), block.Header.Number); ok {
+				continue
+			}
+			res, err := b.rpcGetTransaction(ctx, hash)
+			if err != nil {
+				log.Warn("prefetch GetTransaction err:", err.Error(), hash.Hex())
+				continue
+			}
+			b.txCache.store(hash.Hex(), block.Header.Number, res)
+		}
+	}
+}
+
+// getTransaction returns the transaction from the prefetch cache if any, otherwise from the node
+func (b *BlockParser) getTransaction(hash types.Hash) (*types.TransactionWithStatus, error) {
+	if res, ok := b.txCache.load(hash.Hex(), 0); ok {
+		return res, nil
+	}
+	return b.rpcGetTransaction(b.ctx, hash)
+}
+
+func (b *BlockParser) rpcGetTransaction(ctx context.Context, hash types.Hash) (*types.TransactionWithStatus, error) {
+	start := time.Now()
+	res, err := b.blockSource.GetTransaction(ctx, hash)
+	metrics.ObserveRpc(b.network, "GetTransaction", start, err)
+	return res, err
+}
+
+func (b *BlockParser) getBlockByNumber(ctx context.Context, blockNumber uint64) (*types.Block, error) {
+	start := time.Now()
+	block, err := b.blockSource.GetBlockByNumber(ctx, blockNumber)
+	metrics.ObserveRpc(b.network, "GetBlockByNumber", start, err)
+	return block, err
+}
+
+// getTipBlockNumber returns the tip pushed by the subscription if it is up, otherwise asks the block source
+func (b *BlockParser) getTipBlockNumber() (uint64, error) {
+	if b.tipSubscriber != nil {
+		if tip, ok := b.tipSubscriber.tip(); ok {
+			return tip, nil
+		}
+	}
+	start := time.Now()
+	blockNumber, err := b.blockSource.GetTipBlockNumber(b.ctx)
+	metrics.ObserveRpc(b.network, "GetTipBlockNumber", start, err)
+	return blockNumber, err
+}
@@ -0,0 +1,276 @@
+package block_parser
+
+import (
+	"das_database/config"
+	"das_database/dao"
+	"das_database/metrics"
+	"das_database/notify"
+	"fmt"
+	"github.com/nervosnetwork/ckb-sdk-go/types"
+	"time"
+)
+
+const (
+	PolicyHalt       = "halt"
+	PolicyQuarantine = "quarantine"
+	PolicyContinue   = "continue"
+
+	defaultBackoffMin = time.Second
+	defaultBackoffMax = time.Minute * 5
+)
+
+// retryState tracks the failures of the block the parser is stuck on, only used by the parser goroutine
+type retryState struct {
+	blockNumber  uint64
+	since        time.Time
+	failures     int            // failures of the block
+	txFailures   map[string]int // failures per tx hash
+	stuckAlerted bool
+}
+
+func (r *retryState) init(blockNumber uint64) {
+	if r.txFailures == nil || r.blockNumber != blockNumber {
+		*r = retryState{blockNumber: blockNumber, since: time.Now(), txFailures: make(map[string]int)}
+	}
+}
+
+func (r *retryState) failBlock(blockNumber uint64) {
+	r.init(blockNumber)
+	r.failures++
+}
+
+func (r *retryState) failTx(blockNumber uint64, txHash string) int {
+	r.init(blockNumber)
+	r.txFailures[txHash]++
+	return r.txFailures[txHash]
+}
+
+func (r *retryState) reset() {
+	*r = retryState{}
+}
+
+// policy returns the settings of the network of the parser, read again on every call to follow the config updates
+func (b *BlockParser) policy() config.Policy {
+	return config.Cfg.Policy(b.network)
+}
+
+func actionPolicy(cfg config.ParserPolicy, action string) string {
+	if policy, ok := cfg.Actions[action]; ok {
+		return policy
+	}
+	if cfg.DefaultPolicy != "" {
+		return cfg.DefaultPolicy
+	}
+	return PolicyHalt
+}
+
+// backoff returns the delay before retrying the block after its n-th failure
+func backoff(cfg config.ParserPolicy, n int) time.Duration {
+	minDelay, maxDelay := defaultBackoffMin, defaultBackoffMax
+	if cfg.BackoffMin > 0 {
+		minDelay = time.Duration(cfg.BackoffMin) * time.Second
+	}
+	if cfg.BackoffMax > 0 {
+		maxDelay = time.Duration(cfg.BackoffMax) * time.Second
+	}
+	delay := minDelay
+	for i := 1; i < n && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// handleTransaction runs the handler of the tx in a savepoint of the block transaction,
+// once the tx failed max_retry times its action policy decides whether the block goes on without it.
+// It returns the handled tx for the post commit hooks, nil if the tx is skipped.
+func (b *BlockParser) handleTransaction(blockDao *dao.DbDao, handle FuncTransactionHandle, req FuncTransactionHandleReq) (*PostCommitTx, error) {
+	policy := b.policy()
+	var collector *dao.RowCollector
+	if len(b.postCommitHooks) > 0 || policy.Events.Enable || policy.ChangeFeed.Enable {
+		collector = &dao.RowCollector{}
+	}
+	start := time.Now()
+	err := blockDao.Transaction(func(txDao *dao.DbDao) error {
+		req.DbDao = txDao.WithAction(req.Action, req.TxHash, req.BlockTimestamp)
+		if collector != nil {
+			req.DbDao = req.DbDao.WithRowCollector(collector)
+		}
+		if resp := handle(req); resp.Err != nil {
+			return resp.Err
+		}
+		if err := b.createEventOutbox(policy, txDao, req, collector); err != nil {
+			return err
+		}
+		return createChangeList(policy, txDao, req, collector)
+	})
+	metrics.ObserveActionHandle(b.network, req.Action, start, err)
+	if err == nil {
+		tx := PostCommitTx{Tx: req.Tx, TxHash: req.TxHash, Action: req.Action}
+		if collector != nil {
+			tx.Rows = collector.Rows()
+		}
+		return &tx, nil
+	}
+
+	log.Error("action handle resp:", req.Action, req.BlockNumber, req.TxHash, err.Error())
+	failures := b.retry.failTx(req.BlockNumber, req.TxHash)
+	notify.Send(notify.Message{
+		Severity: notify.SeverityError,
+		Title:    b.notifyTitle("DasDatabase BlockParser"),
+		Text: fmt.Sprintf("> Transaction hash：%s\n> Action：%s\n> Timestamp：%s\n> Error message：%s",
+			req.TxHash, req.Action, time.Now().Format("2006-01-02 15:04:05"), err.Error()),
+		Key: b.network + req.Action + req.TxHash,
+	})
+
+	maxRetry := policy.ParserPolicy.MaxRetry
+	if maxRetry <= 0 || failures < maxRetry {
+		return nil, err
+	}
+	switch policy := actionPolicy(policy.ParserPolicy, req.Action); policy {
+	case PolicyQuarantine:
+		if e := blockDao.CreateDeadLetterInfo(dao.TableDeadLetterInfo{
+			BlockNumber:    req.BlockNumber,
+			BlockTimestamp: req.BlockTimestamp,
+			TxHash:         req.TxHash,
+			Action:         req.Action,
+			ErrMsg:         err.Error(),
+			RetryCount:     failures,
+			Status:         dao.DeadLetterStatusQuarantined,
+		}); e != nil {
+			return nil, fmt.Errorf("CreateDeadLetterInfo err: %s", e.Error())
+		}
+		log.Warn("handleTransaction quarantine:", req.Action, req.BlockNumber, req.TxHash)
+		b.notifyPolicy(notify.SeverityCritical, policy, req, failures, err)
+		return nil, nil
+	case PolicyContinue:
+		log.Warn("handleTransaction continue:", req.Action, req.BlockNumber, req.TxHash)
+		b.notifyPolicy(notify.SeverityCritical, policy, req, failures, err)
+		return nil, nil
+	default:
+		if !b.state.isHalted() {
+			b.state.setHalted(true)
+			log.Warn("handleTransaction halt:", req.Action, req.BlockNumber, req.TxHash)
+			b.notifyPolicy(notify.SeverityCritical, PolicyHalt, req, failures, err)
+		}
+		return nil, err
+	}
+}
+
+func (b *BlockParser) notifyPolicy(severity notify.Severity, policy string, req FuncTransactionHandleReq, failures int, err error) {
+	notify.Send(notify.Message{
+		Severity: severity,
+		Title:    b.notifyTitle("DasDatabase BlockParser " + policy),
+		Text: fmt.Sprintf("> Block number：%d\n> Transaction hash：%s\n> Action：%s\n> Failures：%d\n> Error message：%s",
+			req.BlockNumber, req.TxHash, req.Action, failures, err.Error()),
+		Key: b.network + policy + req.TxHash,
+	})
+}
+
+// notifyTitle prefixes the title of an alert with the network of the parser, its key is prefixed likewise
+func (b *BlockParser) notifyTitle(title string) string {
+	if b.network == "" {
+		return title
+	}
+	return fmt.Sprintf("[%s] %s", b.network, title)
+}
+
+// checkStuck alerts once when the parser failed on the same block for longer than stuck_alert
+func (b *BlockParser) checkStuck() {
+	stuckAlert := time.Duration(b.policy().ParserPolicy.StuckAlert) * time.Second
+	if stuckAlert <= 0 || b.retry.failures == 0 || b.retry.stuckAlerted || time.Since(b.retry.since) < stuckAlert {
+		return
+	}
+	b.retry.stuckAlerted = true
+	notify.Send(notify.Message{
+		Severity: notify.SeverityCritical,
+		Title:    b.notifyTitle("DasDatabase BlockParser stuck"),
+		Text: fmt.Sprintf("> Block number：%d\n> Since：%s\n> Failures：%d",
+			b.retry.blockNumber, b.retry.since.Format("2006-01-02 15:04:05"), b.retry.failures),
+		Key: fmt.Sprintf("%sstuck%d", b.network, b.retry.blockNumber),
+	})
+}
+
+// waitRetry sleeps the backoff of the current failures, it returns false if the parser is stopped meanwhile
+func (b *BlockParser) waitRetry() bool {
+	timer := time.NewTimer(backoff(b.policy().ParserPolicy, b.retry.failures))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-b.ctx.Done():
+		return false
+	}
+}
+
+// Resume lets a halted parser retry again, the failures counted so far are forgotten
+func (b *BlockParser) Resume() {
+	b.state.setHalted(false)
+}
+
+// reprocessDeadLetters re-runs the released txs under their original block number
+func (b *BlockParser) reprocessDeadLetters() {
+	list, err := b.dbDao.FindDeadLetterList(dao.DeadLetterStatusReleased, 10, 0)
+	if err != nil {
+		log.Error("FindDeadLetterList err:", err.Error())
+		return
+	}
+	for _, v := range list {
+		if err = b.reprocessDeadLetter(v); err != nil {
+			log.Error("reprocessDeadLetter err:", v.TxHash, err.Error())
+			if err = b.dbDao.UpdateDeadLetterFailed(v.Id, err.Error()); err != nil {
+				log.Error("UpdateDeadLetterFailed err:", err.Error())
+			}
+		} else {
+			log.Info("reprocessDeadLetter ok:", v.Action, v.BlockNumber, v.TxHash)
+		}
+	}
+}
+
+func (b *BlockParser) reprocessDeadLetter(deadLetter dao.TableDeadLetterInfo) error {
+	handle, ok := b.mapTransactionHandle[deadLetter.Action]
+	if !ok {
+		return fmt.Errorf("no handle of action: %s", deadLetter.Action)
+	}
+	res, err := b.rpcGetTransaction(b.ctx, types.HexToHash(deadLetter.TxHash))
+	if err != nil {
+		return fmt.Errorf("GetTransaction err: %s", err.Error())
+	}
+	return b.handleTransactionOutOfBlock(handle, FuncTransactionHandleReq{
+		Tx:             res.Transaction,
+		TxHash:         deadLetter.TxHash,
+		BlockNumber:    deadLetter.BlockNumber,
+		BlockTimestamp: deadLetter.BlockTimestamp,
+		Action:         deadLetter.Action,
+	}, func(txDao *dao.DbDao) error {
+		return txDao.UpdateDeadLetterProcessed(deadLetter.Id)
+	})
+}
+
+// createEventOutbox writes the domain events of the handled tx in its savepoint, so that they are committed with the block
+func (b *BlockParser) createEventOutbox(policy config.Policy, txDao *dao.DbDao, req FuncTransactionHandleReq, collector *dao.RowCollector) error {
+	if !policy.Events.Enable || collector == nil {
+		return nil
+	}
+	list, err := buildEventList(b.mapEventDef, req, collector.Rows())
+	if err != nil {
+		return err
+	}
+	if err = txDao.CreateEventOutbox(list); err != nil {
+		return fmt.Errorf("CreateEventOutbox err: %s", err.Error())
+	}
+	return nil
+}
+
+// createChangeList appends the rows written by the handled tx to the change feed
+func createChangeList(policy config.Policy, txDao *dao.DbDao, req FuncTransactionHandleReq, collector *dao.RowCollector) error {
+	if !policy.ChangeFeed.Enable || collector == nil {
+		return nil
+	}
+	if err := txDao.CreateChangeList(req.BlockNumber, req.TxHash, req.Action, collector.Rows()); err != nil {
+		return fmt.Errorf("CreateChangeList err: %s", err.Error())
+	}
+	return nil
+}
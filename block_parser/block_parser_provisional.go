@@ -0,0 +1,104 @@
+package block_parser
+
+import (
+	"das_database/dao"
+	"errors"
+	"fmt"
+	"github.com/dotbitHQ/das-lib/witness"
+	"github.com/nervosnetwork/ckb-sdk-go/types"
+)
+
+var errProvisionalScratch = errors.New("provisional scratch")
+
+// parseProvisionalBlocks rebuilds t_provisional_change from the blocks after the cursor up to the tip, the ones
+// the parser waits confirm_num blocks for. It is called once the parser is caught up, and does nothing unless
+// the tip or the cursor moved since the last build. A reorg of these blocks changes the tip hash, so they are rebuilt.
+func (b *BlockParser) parseProvisionalBlocks(tip uint64) error {
+	from := b.currentBlockNumber
+	if from > tip {
+		return nil
+	}
+	tipBlock, err := b.getBlockByNumber(b.ctx, tip)
+	if err != nil {
+		return fmt.Errorf("GetBlockByNumber err: %s", err.Error())
+	}
+	key := fmt.Sprintf("%d-%s", from, tipBlock.Header.Hash.Hex())
+	if key == b.provisionalKey {
+		return nil
+	}
+
+	blocks := make([]*types.Block, 0, tip-from+1)
+	for n := from; n < tip; n++ {
+		block, err := b.getBlockByNumber(b.ctx, n)
+		if err != nil {
+			return fmt.Errorf("GetBlockByNumber err: %s", err.Error())
+		}
+		blocks = append(blocks, block)
+	}
+	blocks = append(blocks, tipBlock)
+	// the chain changed while fetching, or a reorg below the cursor the parser has to roll back first
+	parent, err := b.dbDao.FindBlockInfoByBlockNumber(from - 1)
+	if err != nil {
+		return fmt.Errorf("FindBlockInfoByBlockNumber err: %s", err.Error())
+	}
+	for i, block := range blocks {
+		parentHash := parent.BlockHash
+		if i > 0 {
+			parentHash = blocks[i-1].Header.Hash.Hex()
+		}
+		if parentHash != "" && block.Header.ParentHash.Hex() != parentHash {
+			log.Warn("parseProvisionalBlocks fork:", block.Header.Number, block.Header.ParentHash.Hex(), parentHash)
+			return nil
+		}
+	}
+
+	// the handlers run in a db transaction rolled back afterwards, only the rows they wrote are kept
+	var list []dao.TableProvisionalChange
+	err = b.dbDao.Transaction(func(txDao *dao.DbDao) error {
+		for _, block := range blocks {
+			blockHash := block.Header.Hash.Hex()
+			for _, tx := range block.Transactions {
+				builder, err := witness.ActionDataBuilderFromTx(tx)
+				if err != nil {
+					continue
+				}
+				handle, ok := b.mapTransactionHandle[builder.Action]
+				if !ok {
+					continue
+				}
+				txHash := tx.Hash.Hex()
+				collector := &dao.RowCollector{}
+				// a failed tx is left out of the provisional state, the parser deals with it once confirmed
+				err = txDao.Transaction(func(spDao *dao.DbDao) error {
+					return handle(FuncTransactionHandleReq{
+						DbDao:          spDao.WithAction(builder.Action, txHash, block.Header.Timestamp).WithRowCollector(collector),
+						Tx:             tx,
+						TxHash:         txHash,
+						BlockNumber:    block.Header.Number,
+						BlockTimestamp: block.Header.Timestamp,
+						Action:         builder.Action,
+					}).Err
+				})
+				if err != nil {
+					log.Warn("provisional action handle err:", builder.Action, block.Header.Number, txHash, err.Error())
+					continue
+				}
+				changes, err := dao.ProvisionalChangeList(block.Header.Number, blockHash, txHash, builder.Action, collector.Rows())
+				if err != nil {
+					return fmt.Errorf("ProvisionalChangeList err: %s", err.Error())
+				}
+				list = append(list, changes...)
+			}
+		}
+		return errProvisionalScratch
+	})
+	if err != errProvisionalScratch {
+		return err
+	}
+	if err = b.dbDao.ReplaceProvisionalChanges(list); err != nil {
+		return fmt.Errorf("ReplaceProvisionalChanges err: %s", err.Error())
+	}
+	b.provisionalKey = key
+	log.Info("parseProvisionalBlocks:", from, tip, len(list))
+	return nil
+}
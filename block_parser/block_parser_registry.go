@@ -0,0 +1,49 @@
+package block_parser
+
+import (
+	"das_database/dao"
+	"github.com/dotbitHQ/das-lib/common"
+	"github.com/nervosnetwork/ckb-sdk-go/types"
+)
+
+// PostCommitTx is a tx of a committed block and the rows its handler wrote
+type PostCommitTx struct {
+	Tx     *types.Transaction
+	TxHash string
+	Action common.DasAction
+	Rows   []dao.RowChange
+}
+
+type PostCommitReq struct {
+	BlockNumber    uint64
+	BlockHash      string // empty for the dead letters re-processed out of their block
+	BlockTimestamp uint64
+	Txs            []PostCommitTx
+}
+
+// FuncPostCommitHook is called once the txs are committed, its error is only logged
+type FuncPostCommitHook func(PostCommitReq) error
+
+// RegisterActionHandle registers the handler of the action, replacing the built-in one if any.
+// The handler writes through req.DbDao so that its writes belong to the block transaction.
+// It must be called before RunParser.
+func (b *BlockParser) RegisterActionHandle(action common.DasAction, handle FuncTransactionHandle) {
+	b.mapTransactionHandle[action] = handle
+}
+
+// AddPostCommitHook adds a hook called with the handled txs of every committed block, in the order of addition.
+// It must be called before RunParser.
+func (b *BlockParser) AddPostCommitHook(hook FuncPostCommitHook) {
+	b.postCommitHooks = append(b.postCommitHooks, hook)
+}
+
+func (b *BlockParser) runPostCommitHooks(req PostCommitReq) {
+	if len(req.Txs) == 0 {
+		return
+	}
+	for _, hook := range b.postCommitHooks {
+		if err := hook(req); err != nil {
+			log.Error("post commit hook err:", req.BlockNumber, err.Error())
+		}
+	}
+}
@@ -0,0 +1,508 @@
+package block_parser
+
+import (
+	"bytes"
+	"das_database/dao"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dotbitHQ/das-lib/common"
+	"github.com/dotbitHQ/das-lib/core"
+	"github.com/dotbitHQ/das-lib/witness"
+	"github.com/nervosnetwork/ckb-sdk-go/indexer"
+	"github.com/nervosnetwork/ckb-sdk-go/types"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	repairSearchLimit = 200
+	// repairMaxRpcCalls bounds the node rpc calls of the walk of a cell history
+	repairMaxRpcCalls = 2000
+)
+
+var errRepairScratch = errors.New("repair scratch")
+
+type RepairTx struct {
+	BlockNumber uint64           `json:"block_number"`
+	TxHash      string           `json:"tx_hash"`
+	Action      common.DasAction `json:"action"`
+}
+
+// RepairDiff is a field of a row changed by the repair, Field is empty if the whole row is added or removed
+type RepairDiff struct {
+	Table string `json:"table"`
+	Key   string `json:"key"`
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+type RepairResult struct {
+	AccountId   string       `json:"account_id"`
+	Account     string       `json:"account"`
+	BlockNumber uint64       `json:"block_number"` // the state is rebuilt up to the parser cursor
+	Txs         []RepairTx   `json:"txs"`
+	Diff        []RepairDiff `json:"diff"`
+	Applied     bool         `json:"applied"`
+}
+
+type repairTx struct {
+	tx             *types.Transaction
+	blockNumber    uint64
+	blockTimestamp uint64
+	action         common.DasAction
+}
+
+// repairWalk walks the cell history of an account, aborted past repairMaxRpcCalls
+type repairWalk struct {
+	b        *BlockParser
+	rpcCalls int
+}
+
+func (w *repairWalk) spend() error {
+	if w.rpcCalls++; w.rpcCalls > repairMaxRpcCalls {
+		return fmt.Errorf("cell history walk aborted after %d rpc calls", repairMaxRpcCalls)
+	}
+	return nil
+}
+
+// RepairAccount rebuilds the rows of an account (account info, records, sale listing, offers, smt of a sub-account)
+// by replaying the txs of its cell history through the handlers, up to the parser cursor.
+// account is an account name or id, outpoint the cell to walk the history from, the indexed one by default.
+// The txs are replayed into an empty state in a db transaction rolled back afterwards,
+// the rows of the account are then replaced by the result unless dryRun.
+// The replacement is not journaled under a block: a rollback of a block at or before the cursor touching the account
+// restores its rows journaled by the parser, undoing the repair, which has to be run again after the reorg.
+func (b *BlockParser) RepairAccount(account, outpoint string, dryRun bool) (*RepairResult, error) {
+	if !atomic.CompareAndSwapInt32(&b.repairRunning, 0, 1) {
+		return nil, fmt.Errorf("a repair is already running")
+	}
+	defer atomic.StoreInt32(&b.repairRunning, 0)
+	var res *RepairResult
+	err := b.dasEnv.Run(func() (err error) {
+		res, err = b.repairAccount(account, outpoint, dryRun)
+		return err
+	})
+	return res, err
+}
+
+func (b *BlockParser) repairAccount(account, outpoint string, dryRun bool) (*RepairResult, error) {
+	accountId := account
+	if !strings.HasPrefix(account, common.HexPreFix) {
+		accountId = common.Bytes2Hex(common.GetAccountIdByAccount(account))
+	}
+	block, err := b.dbDao.FindBlockInfo()
+	if err != nil {
+		return nil, fmt.Errorf("FindBlockInfo err: %s", err.Error())
+	} else if block.Id == 0 {
+		return nil, fmt.Errorf("no block indexed yet")
+	}
+	old, err := b.dbDao.FindAccountState(accountId)
+	if err != nil {
+		return nil, fmt.Errorf("FindAccountState err: %s", err.Error())
+	}
+	res := RepairResult{AccountId: accountId, Account: old.AccountInfo.Account, BlockNumber: block.BlockNumber}
+	if res.Account == "" && accountId != account {
+		res.Account = account
+	}
+
+	// the sub-accounts live in the smt of the sub-account cell of their parent
+	var txs []*repairTx
+	w := repairWalk{b: b}
+	parentAccountId := old.AccountInfo.ParentAccountId
+	if parentAccountId == "" && strings.Count(res.Account, ".") > 1 {
+		parentAccountId = common.Bytes2Hex(common.GetAccountIdByAccount(res.Account[strings.Index(res.Account, ".")+1:]))
+	}
+	if parentAccountId != "" {
+		if txs, err = w.subAccountCellTxs(accountId, parentAccountId, block.BlockNumber); err != nil {
+			return nil, err
+		}
+	} else {
+		if outpoint == "" {
+			outpoint = old.AccountInfo.Outpoint
+		}
+		if outpoint == "" {
+			return nil, fmt.Errorf("account not indexed, the outpoint of one of its cells is required: %s", account)
+		}
+		if txs, err = w.accountCellTxs(accountId, outpoint, block.BlockNumber); err != nil {
+			return nil, err
+		}
+	}
+	for _, v := range txs {
+		res.Txs = append(res.Txs, RepairTx{BlockNumber: v.blockNumber, TxHash: v.tx.Hash.Hex(), Action: v.action})
+	}
+
+	// replay into an empty state, the other accounts written by the txs are restored by the rollback
+	var state dao.AccountState
+	err = b.dbDao.Transaction(func(txDao *dao.DbDao) error {
+		if err := txDao.ClearAccountState(accountId); err != nil {
+			return fmt.Errorf("ClearAccountState err: %s", err.Error())
+		}
+		for _, v := range txs {
+			handle, ok := b.mapTransactionHandle[v.action]
+			if !ok {
+				continue
+			}
+			txHash := v.tx.Hash.Hex()
+			if resp := handle(FuncTransactionHandleReq{
+				DbDao:          txDao.WithAction(string(v.action), txHash, v.blockTimestamp),
+				Tx:             v.tx,
+				TxHash:         txHash,
+				BlockNumber:    v.blockNumber,
+				BlockTimestamp: v.blockTimestamp,
+				Action:         v.action,
+			}); resp.Err != nil {
+				return fmt.Errorf("action handle err: %s [%d %s %s]", resp.Err.Error(), v.blockNumber, v.action, txHash)
+			}
+		}
+		var err error
+		if state, err = txDao.FindAccountState(accountId); err != nil {
+			return fmt.Errorf("FindAccountState err: %s", err.Error())
+		}
+		return errRepairScratch
+	})
+	if err != errRepairScratch {
+		return nil, err
+	}
+	// the offers are not in the cell history of the account, the ones no more live are dropped
+	if state.Offers, err = b.liveOffers(state.Offers); err != nil {
+		return nil, err
+	}
+
+	if res.Diff, err = diffAccountState(old, state); err != nil {
+		return nil, fmt.Errorf("diffAccountState err: %s", err.Error())
+	}
+	if dryRun || len(res.Diff) == 0 {
+		return &res, nil
+	}
+	collector := &dao.RowCollector{}
+	err = b.dbDao.Transaction(func(txDao *dao.DbDao) error {
+		if err := txDao.WithRowCollector(collector).ReplaceAccountState(accountId, old, state); err != nil {
+			return fmt.Errorf("ReplaceAccountState err: %s", err.Error())
+		}
+		if !b.policy().ChangeFeed.Enable {
+			return nil
+		}
+		if err := txDao.CreateChangeList(block.BlockNumber, "", dao.ChangeActionRepair, collector.Rows()); err != nil {
+			return fmt.Errorf("CreateChangeList err: %s", err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	res.Applied = true
+	log.Warn("RepairAccount:", accountId, res.Account, len(res.Txs), len(res.Diff))
+	return &res, nil
+}
+
+// getRepairTx returns a committed tx with its block
+func (w *repairWalk) getRepairTx(hash types.Hash) (*repairTx, error) {
+	if err := w.spend(); err != nil {
+		return nil, err
+	}
+	b := w.b
+	res, err := b.rpcGetTransaction(b.ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("GetTransaction err: %s", err.Error())
+	} else if res.TxStatus == nil || res.TxStatus.BlockHash == nil {
+		return nil, fmt.Errorf("transaction not committed: %s", hash.Hex())
+	}
+	header, err := b.dasCore.Client().GetHeader(b.ctx, *res.TxStatus.BlockHash)
+	if err != nil {
+		return nil, fmt.Errorf("GetHeader err: %s", err.Error())
+	}
+	t := repairTx{tx: res.Transaction, blockNumber: header.Number, blockTimestamp: header.Timestamp}
+	if builder, err := witness.ActionDataBuilderFromTx(res.Transaction); err == nil {
+		t.action = builder.Action
+	}
+	return &t, nil
+}
+
+// accountCellTxs walks the account cell from the cell at outpoint back to its registration through the old
+// data of the witnesses, and forward to its cell at the block cursor through the indexer,
+// adding the edits of its sale listings, which do not touch the account cell
+func (w *repairWalk) accountCellTxs(accountId, outpoint string, cursor uint64) ([]*repairTx, error) {
+	op := common.String2OutPointStruct(outpoint)
+	start, err := w.getRepairTx(op.TxHash)
+	if err != nil {
+		return nil, err
+	}
+	if builderMap, err := witness.AccountIdCellDataBuilderFromTx(start.tx, common.DataTypeNew); err != nil {
+		return nil, fmt.Errorf("AccountIdCellDataBuilderFromTx err: %s", err.Error())
+	} else if builder, ok := builderMap[accountId]; !ok || uint(builder.Index) != op.Index {
+		return nil, fmt.Errorf("outpoint is not an account cell of %s: %s", accountId, outpoint)
+	}
+
+	var back []*repairTx
+	for t := start; t != nil; {
+		back = append(back, t)
+		oldMap, err := witness.AccountIdCellDataBuilderFromTx(t.tx, common.DataTypeOld)
+		if err != nil {
+			return nil, fmt.Errorf("AccountIdCellDataBuilderFromTx err: %s", err.Error())
+		}
+		prev, ok := oldMap[accountId]
+		if !ok {
+			break // registered by the tx
+		} else if int(prev.Index) >= len(t.tx.Inputs) {
+			return nil, fmt.Errorf("old account cell index out of range: %s", t.tx.Hash.Hex())
+		}
+		if t, err = w.getRepairTx(t.tx.Inputs[prev.Index].PreviousOutput.TxHash); err != nil {
+			return nil, err
+		}
+	}
+	var txs []*repairTx
+	for i := len(back) - 1; i >= 0; i-- {
+		if back[i].blockNumber <= cursor {
+			txs = append(txs, back[i])
+		}
+	}
+
+	for cur, curTx := op, start; curTx.blockNumber <= cursor; {
+		next, err := w.consumerTx(cur, curTx, cursor)
+		if err != nil {
+			return nil, err
+		} else if next == nil {
+			break
+		}
+		txs = append(txs, next)
+		builderMap, err := witness.AccountIdCellDataBuilderFromTx(next.tx, common.DataTypeNew)
+		if err != nil {
+			return nil, fmt.Errorf("AccountIdCellDataBuilderFromTx err: %s", err.Error())
+		}
+		builder, ok := builderMap[accountId]
+		if !ok {
+			break // recycled
+		}
+		cur, curTx = &types.OutPoint{TxHash: next.tx.Hash, Index: uint(builder.Index)}, next
+	}
+
+	var saleTxs []*repairTx
+	for _, v := range txs {
+		if v.action != common.DasActionStartAccountSale {
+			continue
+		}
+		builder, err := witness.AccountSaleCellDataBuilderFromTx(v.tx, common.DataTypeNew)
+		if err != nil {
+			return nil, fmt.Errorf("AccountSaleCellDataBuilderFromTx err: %s", err.Error())
+		}
+		cur, curTx := &types.OutPoint{TxHash: v.tx.Hash, Index: uint(builder.Index)}, v
+		for {
+			next, err := w.consumerTx(cur, curTx, cursor)
+			if err != nil {
+				return nil, err
+			} else if next == nil || next.action != common.DasActionEditAccountSale {
+				break // cancelled or sold, in the account cell history
+			}
+			saleTxs = append(saleTxs, next)
+			if builder, err = witness.AccountSaleCellDataBuilderFromTx(next.tx, common.DataTypeNew); err != nil {
+				return nil, fmt.Errorf("AccountSaleCellDataBuilderFromTx err: %s", err.Error())
+			}
+			cur, curTx = &types.OutPoint{TxHash: next.tx.Hash, Index: uint(builder.Index)}, next
+		}
+	}
+	txs = append(txs, saleTxs...)
+	sort.SliceStable(txs, func(i, j int) bool {
+		return txs[i].blockNumber < txs[j].blockNumber
+	})
+	return txs, nil
+}
+
+// consumerTx returns the tx spending the cell op of curTx up to the block cursor, nil if none,
+// found among the txs of the indexer with the lock and type of the cell
+func (w *repairWalk) consumerTx(op *types.OutPoint, curTx *repairTx, cursor uint64) (*repairTx, error) {
+	if int(op.Index) >= len(curTx.tx.Outputs) {
+		return nil, fmt.Errorf("output index out of range: %s", common.OutPointStruct2String(op))
+	}
+	output := curTx.tx.Outputs[op.Index]
+	searchKey := indexer.SearchKey{
+		Script:     output.Lock,
+		ScriptType: indexer.ScriptTypeLock,
+		Filter: &indexer.CellsFilter{
+			Script:     output.Type,
+			BlockRange: &[2]uint64{curTx.blockNumber, cursor + 1},
+		},
+	}
+	lastCursor := ""
+	for {
+		if err := w.spend(); err != nil {
+			return nil, err
+		}
+		res, err := w.b.dasCore.Client().GetTransactions(w.b.ctx, &searchKey, indexer.SearchOrderAsc, repairSearchLimit, lastCursor)
+		if err != nil {
+			return nil, fmt.Errorf("GetTransactions err: %s", err.Error())
+		}
+		for _, v := range res.Objects {
+			if v.IoType != indexer.IOTypeIn {
+				continue
+			}
+			t, err := w.getRepairTx(v.TxHash)
+			if err != nil {
+				return nil, err
+			}
+			if int(v.IoIndex) < len(t.tx.Inputs) && *t.tx.Inputs[v.IoIndex].PreviousOutput == *op {
+				return t, nil
+			}
+		}
+		if len(res.Objects) < repairSearchLimit {
+			return nil, nil
+		}
+		lastCursor = res.LastCursor
+	}
+}
+
+// subAccountCellTxs returns the txs of the sub-account cell of the parent up to the block cursor
+// whose witnesses write the sub-account
+func (w *repairWalk) subAccountCellTxs(accountId, parentAccountId string, cursor uint64) ([]*repairTx, error) {
+	contract, err := core.GetDasContractInfo(common.DASContractNameSubAccountCellType)
+	if err != nil {
+		return nil, fmt.Errorf("GetDasContractInfo err: %s", err.Error())
+	}
+	searchKey := indexer.SearchKey{
+		Script:     contract.ToScript(common.Hex2Bytes(parentAccountId)),
+		ScriptType: indexer.ScriptTypeType,
+		Filter: &indexer.CellsFilter{
+			BlockRange: &[2]uint64{0, cursor + 1},
+		},
+	}
+	var txs []*repairTx
+	seen := make(map[types.Hash]struct{})
+	lastCursor := ""
+	for {
+		if err := w.spend(); err != nil {
+			return nil, err
+		}
+		res, err := w.b.dasCore.Client().GetTransactions(w.b.ctx, &searchKey, indexer.SearchOrderAsc, repairSearchLimit, lastCursor)
+		if err != nil {
+			return nil, fmt.Errorf("GetTransactions err: %s", err.Error())
+		}
+		for _, v := range res.Objects {
+			if _, ok := seen[v.TxHash]; ok {
+				continue
+			}
+			seen[v.TxHash] = struct{}{}
+			t, err := w.getRepairTx(v.TxHash)
+			if err != nil {
+				return nil, err
+			}
+			builderMap, err := witness.SubAccountBuilderMapFromTx(t.tx)
+			if err != nil {
+				continue
+			}
+			if _, ok := builderMap[accountId]; ok {
+				txs = append(txs, t)
+			}
+		}
+		if len(res.Objects) < repairSearchLimit {
+			return txs, nil
+		}
+		lastCursor = res.LastCursor
+	}
+}
+
+// liveOffers drops the offers whose cell is spent
+func (b *BlockParser) liveOffers(list []dao.TableOfferInfo) ([]dao.TableOfferInfo, error) {
+	var offers []dao.TableOfferInfo
+	for _, v := range list {
+		cell, err := b.dasCore.Client().GetLiveCell(b.ctx, common.String2OutPointStruct(v.Outpoint), false)
+		if err != nil {
+			return nil, fmt.Errorf("GetLiveCell err: %s", err.Error())
+		}
+		if cell.Status == "live" {
+			offers = append(offers, v)
+		}
+	}
+	return offers, nil
+}
+
+// diffAccountState compares the rows by table, the generated columns left aside
+func diffAccountState(old, state dao.AccountState) ([]RepairDiff, error) {
+	var accountOld, accountNew []dao.TableAccountInfo
+	if old.AccountInfo.Id > 0 {
+		accountOld = append(accountOld, old.AccountInfo)
+	}
+	if state.AccountInfo.Id > 0 {
+		accountNew = append(accountNew, state.AccountInfo)
+	}
+	tables := []struct {
+		name     string
+		key      string // the whole row if empty
+		old, new interface{}
+	}{
+		{dao.TableNameAccountInfo, "account_id", accountOld, accountNew},
+		{dao.TableNameRecordsInfo, "", old.Records, state.Records},
+		{dao.TableNameTradeInfo, "account_id", old.Trades, state.Trades},
+		{dao.TableNameOfferInfo, "outpoint", old.Offers, state.Offers},
+		{dao.TableNameSmtInfo, "account_id", old.Smts, state.Smts},
+	}
+	var list []RepairDiff
+	for _, t := range tables {
+		oldRows, err := repairRows(t.old, t.key)
+		if err != nil {
+			return nil, err
+		}
+		newRows, err := repairRows(t.new, t.key)
+		if err != nil {
+			return nil, err
+		}
+		for key, o := range oldRows {
+			n, ok := newRows[key]
+			if !ok {
+				list = append(list, RepairDiff{Table: t.name, Key: key, Old: repairJson(o)})
+				continue
+			}
+			for field, v := range o {
+				if ov, nv := fmt.Sprint(v), fmt.Sprint(n[field]); ov != nv {
+					list = append(list, RepairDiff{Table: t.name, Key: key, Field: field, Old: ov, New: nv})
+				}
+			}
+		}
+		for key, n := range newRows {
+			if _, ok := oldRows[key]; !ok {
+				list = append(list, RepairDiff{Table: t.name, Key: key, New: repairJson(n)})
+			}
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Table != list[j].Table {
+			return list[i].Table < list[j].Table
+		} else if list[i].Key != list[j].Key {
+			return list[i].Key < list[j].Key
+		}
+		return list[i].Field < list[j].Field
+	})
+	return list, nil
+}
+
+// repairRows decodes a slice of rows by key, without the columns set by the db
+func repairRows(rows interface{}, key string) (map[string]map[string]interface{}, error) {
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+	var list []map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err = dec.Decode(&list); err != nil {
+		return nil, err
+	}
+	res := make(map[string]map[string]interface{})
+	for _, v := range list {
+		delete(v, "id")
+		delete(v, "created_at")
+		delete(v, "updated_at")
+		if key == "" {
+			res[repairJson(v)] = v
+		} else {
+			res[fmt.Sprint(v[key])] = v
+		}
+	}
+	return res, nil
+}
+
+func repairJson(row map[string]interface{}) string {
+	data, _ := json.Marshal(row)
+	return string(data)
+}
@@ -0,0 +1,84 @@
+package block_parser
+
+import (
+	"sync"
+	"time"
+)
+
+// parserState is written by the parser loop and read by the http server
+type parserState struct {
+	lock               sync.RWMutex
+	currentBlockNumber uint64
+	tipBlockNumber     uint64
+	isLatest           bool
+	halted             bool
+	lastBlockAt        time.Time
+	errRetryCount      int
+	lastErr            string
+	lastErrAt          time.Time
+}
+
+type ParserState struct {
+	CurrentBlockNumber uint64    `json:"current_block_number"` // the next block to be parsed
+	TipBlockNumber     uint64    `json:"tip_block_number"`
+	IsLatest           bool      `json:"is_latest"`
+	Halted             bool      `json:"halted"`        // halted by the parser policy until resumed
+	LastBlockAt        time.Time `json:"last_block_at"` // time of the last successfully parsed block
+	ErrRetryCount      int       `json:"err_retry_count"`
+	LastErr            string    `json:"last_err"`
+	LastErrAt          time.Time `json:"last_err_at"`
+}
+
+func (s *parserState) setTip(current, tip uint64, isLatest bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.currentBlockNumber = current
+	s.tipBlockNumber = tip
+	s.isLatest = isLatest
+}
+
+func (s *parserState) setHalted(halted bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.halted = halted
+}
+
+func (s *parserState) isHalted() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.halted
+}
+
+func (s *parserState) setBlockParsed() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.lastBlockAt = time.Now()
+}
+
+// setErr counts the consecutive failures of the parser loop, a nil err resets it
+func (s *parserState) setErr(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err == nil {
+		s.errRetryCount = 0
+		return
+	}
+	s.errRetryCount++
+	s.lastErr = err.Error()
+	s.lastErrAt = time.Now()
+}
+
+func (b *BlockParser) State() ParserState {
+	b.state.lock.RLock()
+	defer b.state.lock.RUnlock()
+	return ParserState{
+		CurrentBlockNumber: b.state.currentBlockNumber,
+		TipBlockNumber:     b.state.tipBlockNumber,
+		IsLatest:           b.state.isLatest,
+		Halted:             b.state.halted,
+		LastBlockAt:        b.state.lastBlockAt,
+		ErrRetryCount:      b.state.errRetryCount,
+		LastErr:            b.state.lastErr,
+		LastErrAt:          b.state.lastErrAt,
+	}
+}
@@ -3,6 +3,8 @@ package block_parser
 import (
 	"context"
 	"das_database/config"
+	"das_database/dao"
+	"encoding/json"
 	"fmt"
 	"github.com/dotbitHQ/das-lib/common"
 	"github.com/dotbitHQ/das-lib/witness"
@@ -11,6 +13,7 @@ import (
 	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestBlockNumber(t *testing.T) {
@@ -22,6 +25,34 @@ func TestBlockNumber(t *testing.T) {
 	fmt.Println(blockNumber, blockNumber2)
 }
 
+func TestBackoff(t *testing.T) {
+	cfg := config.ParserPolicy{BackoffMin: 1, BackoffMax: 10}
+	for n, want := range map[int]time.Duration{1: time.Second, 2: time.Second * 2, 4: time.Second * 8, 5: time.Second * 10, 100: time.Second * 10} {
+		if got := backoff(cfg, n); got != want {
+			t.Fatal(n, got, want)
+		}
+	}
+}
+
+func TestBuildEventList(t *testing.T) {
+	list, err := buildEventList(mapEventDef, FuncTransactionHandleReq{Action: common.DasActionBuyAccount, BlockNumber: 1, TxHash: "0x1"}, []dao.RowChange{
+		{Table: dao.TableNameAccountInfo, Operation: dao.RowOperationUpsert, Row: dao.TableAccountInfo{AccountId: "0xa", Account: "a.bit"}},
+		{Table: dao.TableNameTradeInfo, Operation: dao.RowOperationDelete, Row: dao.TableTradeInfo{AccountId: "0xa"}},
+		{Table: dao.TableNameTradeDealInfo, Operation: dao.RowOperationUpsert, Row: dao.TableTradeDealInfo{AccountId: "0xa", Account: "a.bit"}},
+		{Table: dao.TableNameAccountHistory, Operation: dao.RowOperationUpsert, Row: dao.TableAccountHistory{AccountId: "0xa"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].EventType != "AccountSold" || list[0].Account != "a.bit" {
+		t.Fatal(list)
+	}
+	var rows []dao.RowChange
+	if err = json.Unmarshal([]byte(list[0].Payload), &rows); err != nil || len(rows) != 3 {
+		t.Fatal(list[0].Payload)
+	}
+}
+
 func getCkbClient() (rpc.Client, error) {
 	if err := config.InitCfg("../config/config.yaml"); err != nil {
 		panic(fmt.Errorf("InitCfg err: %s", err))
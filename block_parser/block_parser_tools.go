@@ -0,0 +1,134 @@
+package block_parser
+
+import (
+	"das_database/dao"
+	"errors"
+	"fmt"
+	"github.com/dotbitHQ/das-lib/common"
+	"github.com/dotbitHQ/das-lib/witness"
+	"github.com/nervosnetwork/ckb-sdk-go/types"
+)
+
+// handleTransactionOutOfBlock runs the handler of a tx apart from the parsing of its block (dead letters, parse-tx, reindex),
+// its writes are journaled under its block number and fn runs in the same db transaction
+func (b *BlockParser) handleTransactionOutOfBlock(handle FuncTransactionHandle, req FuncTransactionHandleReq, fn func(txDao *dao.DbDao) error) error {
+	policy := b.policy()
+	collector := &dao.RowCollector{}
+	err := b.dbDao.WithBlockNumber(req.BlockNumber).Transaction(func(txDao *dao.DbDao) error {
+		req.DbDao = txDao.WithAction(req.Action, req.TxHash, req.BlockTimestamp).WithRowCollector(collector)
+		if resp := handle(req); resp.Err != nil {
+			return resp.Err
+		}
+		if err := b.createEventOutbox(policy, txDao, req, collector); err != nil {
+			return err
+		}
+		if err := createChangeList(policy, txDao, req, collector); err != nil {
+			return err
+		}
+		if fn != nil {
+			return fn(txDao)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	b.runPostCommitHooks(PostCommitReq{
+		BlockNumber:    req.BlockNumber,
+		BlockTimestamp: req.BlockTimestamp,
+		Txs: []PostCommitTx{{
+			Tx:     req.Tx,
+			TxHash: req.TxHash,
+			Action: req.Action,
+			Rows:   collector.Rows(),
+		}},
+	})
+	return nil
+}
+
+// ErrNotDasTransaction is returned by ParseTransaction for a tx without das witness
+var ErrNotDasTransaction = errors.New("not a das transaction")
+
+// ParseTransaction runs the handler of a committed tx again, it returns the action of the tx,
+// empty if its action has no handler, ErrNotDasTransaction if the tx is not a das tx
+func (b *BlockParser) ParseTransaction(txHash string) (action common.DasAction, err error) {
+	err = b.dasEnv.Run(func() error {
+		action, err = b.parseTransaction(txHash)
+		return err
+	})
+	return action, err
+}
+
+func (b *BlockParser) parseTransaction(txHash string) (common.DasAction, error) {
+	res, err := b.rpcGetTransaction(b.ctx, types.HexToHash(txHash))
+	if err != nil {
+		return "", fmt.Errorf("GetTransaction err: %s", err.Error())
+	} else if res.TxStatus == nil || res.TxStatus.BlockHash == nil {
+		return "", fmt.Errorf("transaction not committed: %s", txHash)
+	}
+	header, err := b.dasCore.Client().GetHeader(b.ctx, *res.TxStatus.BlockHash)
+	if err != nil {
+		return "", fmt.Errorf("GetHeader err: %s", err.Error())
+	}
+	builder, err := witness.ActionDataBuilderFromTx(res.Transaction)
+	if err != nil {
+		return "", fmt.Errorf("%w: ActionDataBuilderFromTx err: %s", ErrNotDasTransaction, err.Error())
+	}
+	handle, ok := b.mapTransactionHandle[builder.Action]
+	if !ok {
+		return "", nil
+	}
+	err = b.handleTransactionOutOfBlock(handle, FuncTransactionHandleReq{
+		Tx:             res.Transaction,
+		TxHash:         txHash,
+		BlockNumber:    header.Number,
+		BlockTimestamp: header.Timestamp,
+		Action:         builder.Action,
+	}, nil)
+	if err != nil {
+		return builder.Action, fmt.Errorf("action handle err: %s", err.Error())
+	}
+	return builder.Action, nil
+}
+
+// ReindexBlocks runs the handlers of the txs of the blocks [from, to] again, in order,
+// the block cursor of the parser is left unchanged
+func (b *BlockParser) ReindexBlocks(from, to uint64) error {
+	return b.dasEnv.Run(func() error {
+		return b.reindexBlocks(from, to)
+	})
+}
+
+func (b *BlockParser) reindexBlocks(from, to uint64) error {
+	for blockNumber := from; blockNumber <= to; blockNumber++ {
+		if err := b.ctx.Err(); err != nil {
+			return err
+		}
+		block, err := b.getBlockByNumber(b.ctx, blockNumber)
+		if err != nil {
+			return fmt.Errorf("GetBlockByNumber err: %s [%d]", err.Error(), blockNumber)
+		}
+		for _, tx := range block.Transactions {
+			builder, err := witness.ActionDataBuilderFromTx(tx)
+			if err != nil {
+				continue
+			}
+			handle, ok := b.mapTransactionHandle[builder.Action]
+			if !ok {
+				continue
+			}
+			txHash := tx.Hash.Hex()
+			if err = b.handleTransactionOutOfBlock(handle, FuncTransactionHandleReq{
+				Tx:             tx,
+				TxHash:         txHash,
+				BlockNumber:    blockNumber,
+				BlockTimestamp: block.Header.Timestamp,
+				Action:         builder.Action,
+			}, nil); err != nil {
+				return fmt.Errorf("action handle err: %s [%d %s %s]", err.Error(), blockNumber, builder.Action, txHash)
+			}
+			log.Info("ReindexBlocks:", blockNumber, builder.Action, txHash)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package block_parser
+
+import (
+	"context"
+	"github.com/nervosnetwork/ckb-sdk-go/rpc"
+	"github.com/nervosnetwork/ckb-sdk-go/types"
+)
+
+// BlockSource provides the blocks to parse and the transactions their handlers look up
+type BlockSource interface {
+	GetTipBlockNumber(ctx context.Context) (uint64, error)
+	GetBlockByNumber(ctx context.Context, blockNumber uint64) (*types.Block, error)
+	GetTransaction(ctx context.Context, hash types.Hash) (*types.TransactionWithStatus, error)
+}
+
+// rpcBlockSource reads from the ckb node
+type rpcBlockSource struct {
+	client rpc.Client
+}
+
+func NewRpcBlockSource(client rpc.Client) BlockSource {
+	return &rpcBlockSource{client: client}
+}
+
+func (r *rpcBlockSource) GetTipBlockNumber(ctx context.Context) (uint64, error) {
+	return r.client.GetTipBlockNumber(ctx)
+}
+
+func (r *rpcBlockSource) GetBlockByNumber(ctx context.Context, blockNumber uint64) (*types.Block, error) {
+	return r.client.GetBlockByNumber(ctx, blockNumber)
+}
+
+func (r *rpcBlockSource) GetTransaction(ctx context.Context, hash types.Hash) (*types.TransactionWithStatus, error) {
+	return r.client.GetTransaction(ctx, hash)
+}
@@ -0,0 +1,322 @@
+package block_parser
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/nervosnetwork/ckb-sdk-go/types"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// archiveWindow bounds how far the archive is read ahead of the block being parsed,
+// the blocks recorded by the concurrent fetch are at most that far out of order.
+// The replay keeps the blocks and the transactions read within that many blocks of the last requested block,
+// the recorder records a transaction again if it is looked up more than half of it after it was recorded,
+// so the concurrent fetch must stay within half of it (chain.concurrency_num < 500).
+const archiveWindow = 1000
+
+// maxRecordedTxs bounds the hashes the recorder remembers to skip the txs already recorded
+const maxRecordedTxs = 100000
+
+// archiveRecord is a line of a block archive, either a block or a transaction looked up by a handler
+type archiveRecord struct {
+	Block *types.Block                 `json:"block,omitempty"`
+	Tx    *types.TransactionWithStatus `json:"tx,omitempty"`
+}
+
+type archiveReader struct {
+	file *os.File
+	gz   *gzip.Reader
+	dec  *json.Decoder
+}
+
+// openArchive opens an NDJSON archive, gzip compressed if its name ends with .gz
+func openArchive(path string) (*archiveReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	a := archiveReader{file: file}
+	var r io.Reader = bufio.NewReader(file)
+	if strings.HasSuffix(path, ".gz") {
+		if a.gz, err = gzip.NewReader(r); err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("gzip.NewReader err: %s", err.Error())
+		}
+		r = a.gz
+	}
+	a.dec = json.NewDecoder(r)
+	return &a, nil
+}
+
+func (a *archiveReader) next() (*archiveRecord, error) {
+	var record archiveRecord
+	if err := a.dec.Decode(&record); err == io.ErrUnexpectedEOF {
+		// the last record was cut by a crash of the recorder
+		log.Warn("archive ends with a partial record")
+		return nil, io.EOF
+	} else if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (a *archiveReader) close() error {
+	if a.gz != nil {
+		_ = a.gz.Close()
+	}
+	return a.file.Close()
+}
+
+// FileBlockSource replays the blocks and transactions of an archive written by BlockRecorder.
+// It reads the archive forward as the parser asks for the next blocks, a block replaced by a reorg
+// while recording is replayed in its last recorded version.
+type FileBlockSource struct {
+	confirmNum     uint64
+	tipBlockNumber uint64
+
+	lock        sync.Mutex
+	reader      *archiveReader
+	eof         bool
+	blocks      map[uint64]*types.Block
+	txs         map[types.Hash]archiveTx
+	lastRead    uint64 // highest block read from the archive
+	lastRequest uint64
+}
+
+// archiveTx is a transaction of the archive, evicted with the blocks before the one read last when it was read
+type archiveTx struct {
+	tx          *types.TransactionWithStatus
+	blockNumber uint64
+}
+
+// NewFileBlockSource opens the archive, its tip is reported confirmNum blocks past its last block
+// so that the parser replays it to the end
+func NewFileBlockSource(path string, confirmNum uint64) (*FileBlockSource, error) {
+	reader, err := openArchive(path)
+	if err != nil {
+		return nil, fmt.Errorf("openArchive err: %s", err.Error())
+	}
+	f := FileBlockSource{
+		confirmNum: confirmNum,
+		blocks:     make(map[uint64]*types.Block),
+		txs:        make(map[types.Hash]archiveTx),
+	}
+	// the tip is the last block of the archive
+	for {
+		record, err := reader.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			_ = reader.close()
+			return nil, fmt.Errorf("read archive err: %s", err.Error())
+		}
+		if record.Block != nil && record.Block.Header.Number > f.tipBlockNumber {
+			f.tipBlockNumber = record.Block.Header.Number
+		}
+	}
+	_ = reader.close()
+
+	if f.reader, err = openArchive(path); err != nil {
+		return nil, fmt.Errorf("openArchive err: %s", err.Error())
+	}
+	return &f, nil
+}
+
+func (f *FileBlockSource) GetTipBlockNumber(ctx context.Context) (uint64, error) {
+	return f.tipBlockNumber + f.confirmNum, nil
+}
+
+func (f *FileBlockSource) GetBlockByNumber(ctx context.Context, blockNumber uint64) (*types.Block, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if blockNumber > f.lastRequest {
+		f.lastRequest = blockNumber
+		for k := range f.blocks {
+			if k+archiveWindow < blockNumber {
+				delete(f.blocks, k)
+			}
+		}
+		for k, v := range f.txs {
+			if v.blockNumber+archiveWindow < blockNumber {
+				delete(f.txs, k)
+			}
+		}
+	}
+	if err := f.readUntil(func() bool {
+		_, ok := f.blocks[blockNumber]
+		return ok
+	}); err != nil {
+		return nil, err
+	}
+	if block, ok := f.blocks[blockNumber]; ok {
+		return block, nil
+	}
+	return nil, fmt.Errorf("block not in archive: %d", blockNumber)
+}
+
+func (f *FileBlockSource) GetTransaction(ctx context.Context, hash types.Hash) (*types.TransactionWithStatus, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if err := f.readUntil(func() bool {
+		_, ok := f.txs[hash]
+		return ok
+	}); err != nil {
+		return nil, err
+	}
+	if v, ok := f.txs[hash]; ok {
+		return v.tx, nil
+	}
+	return nil, fmt.Errorf("transaction not in archive: %s", hash.Hex())
+}
+
+// readUntil reads the archive until found returns true, the end of the archive,
+// or a block further than archiveWindow from the last requested one
+func (f *FileBlockSource) readUntil(found func() bool) error {
+	for !found() && !f.eof {
+		record, err := f.reader.next()
+		if err == io.EOF {
+			f.eof = true
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("read archive err: %s", err.Error())
+		}
+		if record.Tx != nil && record.Tx.Transaction != nil {
+			f.txs[record.Tx.Transaction.Hash] = archiveTx{tx: record.Tx, blockNumber: f.lastRead}
+		}
+		if record.Block != nil {
+			number := record.Block.Header.Number
+			f.blocks[number] = record.Block
+			if number > f.lastRead {
+				f.lastRead = number
+			}
+			if number > f.lastRequest+archiveWindow {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// Close releases the archive
+func (f *FileBlockSource) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.reader.close()
+}
+
+// BlockRecorder appends the blocks and transactions read from its source to an archive,
+// which FileBlockSource replays. The archive is gzip compressed if its name ends with .gz.
+type BlockRecorder struct {
+	BlockSource
+
+	lock sync.Mutex
+	file *os.File
+	gz   *gzip.Writer
+	buf  *bufio.Writer
+	enc  *json.Encoder
+	txs  map[types.Hash]uint64 // highest block recorded when the tx was recorded
+	last uint64                // highest block recorded
+}
+
+func NewBlockRecorder(source BlockSource, path string) (*BlockRecorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	r := BlockRecorder{BlockSource: source, file: file, txs: make(map[types.Hash]uint64)}
+	var w io.Writer = file
+	if strings.HasSuffix(path, ".gz") {
+		// appending starts a new gzip member, gzip readers read them all
+		r.gz = gzip.NewWriter(file)
+		w = r.gz
+	}
+	r.buf = bufio.NewWriter(w)
+	r.enc = json.NewEncoder(r.buf)
+	return &r, nil
+}
+
+func (r *BlockRecorder) GetBlockByNumber(ctx context.Context, blockNumber uint64) (*types.Block, error) {
+	block, err := r.BlockSource.GetBlockByNumber(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.write(archiveRecord{Block: block}); err != nil {
+		log.Error("BlockRecorder write err:", blockNumber, err.Error())
+	}
+	r.lock.Lock()
+	if blockNumber > r.last {
+		r.last = blockNumber
+	}
+	r.lock.Unlock()
+	return block, nil
+}
+
+func (r *BlockRecorder) GetTransaction(ctx context.Context, hash types.Hash) (*types.TransactionWithStatus, error) {
+	tx, err := r.BlockSource.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	r.lock.Lock()
+	// recorded again once the replay may have evicted it, the replay keeps the last one
+	recorded, ok := r.txs[hash]
+	ok = ok && recorded+archiveWindow/2 >= r.last
+	if !ok {
+		if len(r.txs) >= maxRecordedTxs {
+			for k, v := range r.txs {
+				if v+archiveWindow/2 < r.last {
+					delete(r.txs, k)
+				}
+			}
+		}
+		if len(r.txs) >= maxRecordedTxs {
+			r.txs = make(map[types.Hash]uint64)
+		}
+		r.txs[hash] = r.last
+	}
+	r.lock.Unlock()
+	if !ok {
+		if err = r.write(archiveRecord{Tx: tx}); err != nil {
+			log.Error("BlockRecorder write err:", hash.Hex(), err.Error())
+		}
+	}
+	return tx, nil
+}
+
+// write appends the record and flushes it, so that the archive is readable up to the last record if the process dies
+func (r *BlockRecorder) write(record archiveRecord) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if err := r.enc.Encode(record); err != nil {
+		return err
+	}
+	if err := r.buf.Flush(); err != nil {
+		return err
+	}
+	if r.gz != nil {
+		return r.gz.Flush()
+	}
+	return nil
+}
+
+// Close flushes and closes the archive
+func (r *BlockRecorder) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if err := r.buf.Flush(); err != nil {
+		return err
+	}
+	if r.gz != nil {
+		if err := r.gz.Close(); err != nil {
+			return err
+		}
+	}
+	return r.file.Close()
+}
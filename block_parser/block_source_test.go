@@ -0,0 +1,170 @@
+package block_parser
+
+import (
+	"context"
+	"fmt"
+	"github.com/nervosnetwork/ckb-sdk-go/types"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type memBlockSource struct {
+	blocks map[uint64]*types.Block
+	txs    map[types.Hash]*types.TransactionWithStatus
+}
+
+func (m *memBlockSource) GetTipBlockNumber(ctx context.Context) (uint64, error) {
+	return uint64(len(m.blocks)), nil
+}
+
+func (m *memBlockSource) GetBlockByNumber(ctx context.Context, blockNumber uint64) (*types.Block, error) {
+	if block, ok := m.blocks[blockNumber]; ok {
+		return block, nil
+	}
+	return nil, fmt.Errorf("not found")
+}
+
+func (m *memBlockSource) GetTransaction(ctx context.Context, hash types.Hash) (*types.TransactionWithStatus, error) {
+	if tx, ok := m.txs[hash]; ok {
+		return tx, nil
+	}
+	return nil, fmt.Errorf("not found")
+}
+
+func TestBlockRecorder(t *testing.T) {
+	tx := &types.Transaction{Hash: types.HexToHash("0x01"), Witnesses: [][]byte{{1, 2}}}
+	source := &memBlockSource{
+		blocks: map[uint64]*types.Block{
+			1: {Header: &types.Header{Number: 1, Hash: types.HexToHash("0xb1")}},
+			2: {Header: &types.Header{Number: 2, Hash: types.HexToHash("0xb2"), ParentHash: types.HexToHash("0xb1")}, Transactions: []*types.Transaction{tx}},
+		},
+		txs: map[types.Hash]*types.TransactionWithStatus{tx.Hash: {Transaction: tx, TxStatus: &types.TxStatus{Status: types.TransactionStatusCommitted}}},
+	}
+	path := filepath.Join(t.TempDir(), "blocks.ndjson.gz")
+	recorder, err := NewBlockRecorder(source, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, n := range []uint64{2, 1} {
+		if _, err = recorder.GetBlockByNumber(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, err = recorder.GetTransaction(ctx, tx.Hash); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	replay, err := NewFileBlockSource(path, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replay.Close()
+	if tip, _ := replay.GetTipBlockNumber(ctx); tip != 6 {
+		t.Fatal("tip", tip)
+	}
+	block, err := replay.GetBlockByNumber(ctx, 1)
+	if err != nil || block.Header.Hash != source.blocks[1].Header.Hash {
+		t.Fatal(block, err)
+	}
+	block, err = replay.GetBlockByNumber(ctx, 2)
+	if err != nil || block.Transactions[0].Hash != tx.Hash || string(block.Transactions[0].Witnesses[0]) != string(tx.Witnesses[0]) {
+		t.Fatal(block, err)
+	}
+	res, err := replay.GetTransaction(ctx, tx.Hash)
+	if err != nil || res.TxStatus.Status != types.TransactionStatusCommitted {
+		t.Fatal(res, err)
+	}
+	if _, err = replay.GetBlockByNumber(ctx, 3); err == nil {
+		t.Fatal("block 3")
+	}
+}
+
+func TestFileBlockSourceWindow(t *testing.T) {
+	tx := &types.Transaction{Hash: types.HexToHash("0x01")}
+	source := &memBlockSource{
+		blocks: make(map[uint64]*types.Block),
+		txs:    map[types.Hash]*types.TransactionWithStatus{tx.Hash: {Transaction: tx}},
+	}
+	const last = archiveWindow * 3
+	for n := uint64(1); n <= last; n++ {
+		source.blocks[n] = &types.Block{Header: &types.Header{Number: n}}
+	}
+	path := filepath.Join(t.TempDir(), "blocks.ndjson")
+	recorder, err := NewBlockRecorder(source, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	// the tx is looked up by the blocks 1 and last
+	for n := uint64(1); n <= last; n++ {
+		if _, err = recorder.GetBlockByNumber(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+		if n == 1 || n == last {
+			if _, err = recorder.GetTransaction(ctx, tx.Hash); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err = recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	replay, err := NewFileBlockSource(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replay.Close()
+	for n := uint64(1); n <= last; n++ {
+		if _, err = replay.GetBlockByNumber(ctx, n); err != nil {
+			t.Fatal(n, err)
+		}
+		if len(replay.blocks) > archiveWindow*2+2 || len(replay.txs) > 1 {
+			t.Fatal("window", n, len(replay.blocks), len(replay.txs))
+		}
+		if n == 1 || n == last {
+			if _, err = replay.GetTransaction(ctx, tx.Hash); err != nil {
+				t.Fatal(n, err)
+			}
+		}
+	}
+}
+
+func TestBlockPrefetcher(t *testing.T) {
+	source := &memBlockSource{blocks: make(map[uint64]*types.Block)}
+	for n := uint64(1); n <= 10; n++ {
+		source.blocks[n] = &types.Block{Header: &types.Header{Number: n}}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := &BlockParser{blockSource: source, ctx: ctx, fetchWorkerNum: 2}
+	p := newBlockPrefetcher(b, 3)
+	for _, n := range []uint64{1, 2, 3, 4, 5, 2, 3, 9, 10} {
+		block, err := p.take(n, 11)
+		if err != nil || block.Header.Number != n {
+			t.Fatal(n, block, err)
+		}
+		if uint64(len(p.results)) > p.window {
+			t.Fatal("window", len(p.results))
+		}
+	}
+	if _, err := p.take(11, 11); err == nil {
+		t.Fatal("block past the limit")
+	}
+	if _, err := p.take(11, 12); err == nil {
+		t.Fatal("missing block")
+	}
+	// the workers are gone once the parser is stopped
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+	if _, err := p.take(1, 11); err == nil {
+		t.Fatal("take after stop")
+	}
+}
@@ -0,0 +1,272 @@
+package block_parser
+
+import (
+	"das_database/dao"
+	"fmt"
+	"github.com/dotbitHQ/das-lib/common"
+	"github.com/dotbitHQ/das-lib/core"
+	"github.com/dotbitHQ/das-lib/witness"
+	"github.com/nervosnetwork/ckb-sdk-go/types"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultPendingTxInterval = time.Second * 5
+	// the committed and rejected txs are kept for a while, for the clients polling their tx
+	pendingTxRetention = time.Hour * 24
+	// bounds the hashes of the pool remembered not to be das txs
+	maxNonDasTxs = 100000
+)
+
+// RunPendingTxTracker follows the tx pool of the node and tracks the das txs with a handler in t_pending_tx,
+// they are marked committed once the parser indexed them, rejected once they dropped out of the pool.
+// It must be called before RunParser.
+func (b *BlockParser) RunPendingTxTracker(interval time.Duration) {
+	if interval == 0 {
+		interval = defaultPendingTxInterval
+	}
+	b.AddPostCommitHook(func(req PostCommitReq) error {
+		txHashes := make([]string, 0, len(req.Txs))
+		for _, v := range req.Txs {
+			txHashes = append(txHashes, v.TxHash)
+		}
+		if err := b.dbDao.UpdatePendingTxCommitted(txHashes, req.BlockNumber); err != nil {
+			return fmt.Errorf("UpdatePendingTxCommitted err: %s", err.Error())
+		}
+		return nil
+	})
+
+	ticker := time.NewTicker(interval)
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for {
+			select {
+			case <-ticker.C:
+				if err := b.trackPendingTx(); err != nil {
+					log.Error("trackPendingTx err:", err.Error())
+				}
+			case <-b.ctx.Done():
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+}
+
+func (b *BlockParser) trackPendingTx() error {
+	pool, err := b.dasCore.Client().GetRawTxPool(b.ctx)
+	if err != nil {
+		return fmt.Errorf("GetRawTxPool err: %s", err.Error())
+	}
+	inPool := make(map[string]struct{})
+	var txHashes []string
+	for _, list := range [][]*types.Hash{pool.Pending, pool.Proposed} {
+		for _, v := range list {
+			inPool[v.Hex()] = struct{}{}
+			txHashes = append(txHashes, v.Hex())
+		}
+	}
+
+	// the txs seen not to be das txs are forgotten once out of the pool
+	if b.nonDasTxs == nil || len(b.nonDasTxs) >= maxNonDasTxs {
+		b.nonDasTxs = make(map[string]struct{})
+	}
+	for k := range b.nonDasTxs {
+		if _, ok := inPool[k]; !ok {
+			delete(b.nonDasTxs, k)
+		}
+	}
+
+	// new txs of the pool
+	tracked, err := b.dbDao.FindPendingTxHashes(txHashes)
+	if err != nil {
+		return fmt.Errorf("FindPendingTxHashes err: %s", err.Error())
+	}
+	mapTracked := make(map[string]struct{}, len(tracked))
+	for _, v := range tracked {
+		mapTracked[v] = struct{}{}
+	}
+	var list []dao.TablePendingTx
+	for _, v := range txHashes {
+		if _, ok := mapTracked[v]; ok {
+			continue
+		} else if _, ok := b.nonDasTxs[v]; ok {
+			continue
+		}
+		pendingTx, err := b.decodePendingTx(types.HexToHash(v))
+		if err != nil {
+			log.Warn("decodePendingTx err:", v, err.Error())
+			continue
+		} else if pendingTx != nil {
+			list = append(list, *pendingTx)
+		} else {
+			b.nonDasTxs[v] = struct{}{}
+		}
+	}
+	if err = b.dbDao.CreatePendingTx(list); err != nil {
+		return fmt.Errorf("CreatePendingTx err: %s", err.Error())
+	}
+
+	// txs out of the pool, committed or dropped
+	pendingList, err := b.dbDao.FindPendingTxByStatus(dao.PendingTxStatusPending)
+	if err != nil {
+		return fmt.Errorf("FindPendingTxByStatus err: %s", err.Error())
+	}
+	for _, v := range pendingList {
+		if _, ok := inPool[v.TxHash]; ok {
+			continue
+		}
+		if err = b.checkPendingTx(v.TxHash); err != nil {
+			log.Warn("checkPendingTx err:", v.TxHash, err.Error())
+		}
+	}
+
+	if err = b.dbDao.DeletePendingTxBefore(time.Now().Add(-pendingTxRetention)); err != nil {
+		return fmt.Errorf("DeletePendingTxBefore err: %s", err.Error())
+	}
+	return nil
+}
+
+// checkPendingTx marks the tx out of the pool rejected if the node reports it rejected or unknown
+// (a null result, without status, for the nodes before 0.105), or committed if it is in a block
+// the parser already passed (before the tracker saw it committed).
+// It is left pending on an rpc error, to be checked again on the next tick.
+func (b *BlockParser) checkPendingTx(txHash string) error {
+	res, err := b.dasCore.Client().GetTransaction(b.ctx, types.HexToHash(txHash))
+	if err != nil {
+		return fmt.Errorf("GetTransaction err: %s", err.Error())
+	} else if res == nil || res.TxStatus == nil {
+		return fmt.Errorf("GetTransaction: no tx status")
+	}
+	switch res.TxStatus.Status {
+	case types.TransactionStatusRejected, types.TransactionStatusUnknown, "":
+		log.Info("pending tx rejected:", txHash, res.TxStatus.Status)
+		return b.dbDao.UpdatePendingTxRejected(txHash)
+	case types.TransactionStatusCommitted:
+		if res.TxStatus.BlockHash == nil {
+			return nil
+		}
+	default:
+		return nil
+	}
+	header, err := b.dasCore.Client().GetHeader(b.ctx, *res.TxStatus.BlockHash)
+	if err != nil {
+		return fmt.Errorf("GetHeader err: %s", err.Error())
+	}
+	if header.Number < atomic.LoadUint64(&b.currentBlockNumber) {
+		return b.dbDao.UpdatePendingTxCommitted([]string{txHash}, header.Number)
+	}
+	return nil
+}
+
+// decodePendingTx returns nil if the tx is not a das tx the parser handles
+func (b *BlockParser) decodePendingTx(hash types.Hash) (*dao.TablePendingTx, error) {
+	res, err := b.dasCore.Client().GetTransaction(b.ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("GetTransaction err: %s", err.Error())
+	}
+	tx := res.Transaction
+	// only the witness and the contract read the das-lib maps of the network, the rpc calls stay out of its lock
+	var action common.DasAction
+	var dasLockTypeId string
+	err = b.dasEnv.Run(func() error {
+		builder, err := witness.ActionDataBuilderFromTx(tx)
+		if err != nil {
+			return nil
+		} else if _, ok := b.mapTransactionHandle[builder.Action]; !ok {
+			return nil
+		}
+		dasLock, err := core.GetDasContractInfo(common.DasContractNameDispatchCellType)
+		if err != nil {
+			return fmt.Errorf("GetDasContractInfo err: %s", err.Error())
+		}
+		action, dasLockTypeId = builder.Action, dasLock.ContractTypeId.Hex()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	} else if action == "" {
+		return nil, nil
+	}
+
+	pendingTx := dao.TablePendingTx{
+		TxHash: hash.Hex(),
+		Action: action,
+		Status: dao.PendingTxStatusPending,
+	}
+	pendingTx.AccountId, pendingTx.Account = pendingTxAccount(tx, action)
+
+	// the signer, owner of the first das-lock input
+	for _, v := range tx.Inputs {
+		previous, err := b.dasCore.Client().GetTransaction(b.ctx, v.PreviousOutput.TxHash)
+		if err != nil {
+			return nil, fmt.Errorf("GetTransaction err: %s", err.Error())
+		}
+		if int(v.PreviousOutput.Index) >= len(previous.Transaction.Outputs) {
+			continue
+		}
+		lock := previous.Transaction.Outputs[v.PreviousOutput.Index].Lock
+		if lock.CodeHash.Hex() != dasLockTypeId {
+			continue
+		}
+		if ownerHex, _, err := b.dasCore.Daf().ArgsToHex(lock.Args); err == nil {
+			pendingTx.ChainType, pendingTx.Address = ownerHex.ChainType, ownerHex.AddressHex
+			break
+		}
+	}
+	// the receiver, owner of the first das-lock output
+	for _, v := range tx.Outputs {
+		if v.Lock.CodeHash.Hex() != dasLockTypeId {
+			continue
+		}
+		if ownerHex, _, err := b.dasCore.Daf().ArgsToHex(v.Lock.Args); err == nil {
+			pendingTx.ToChainType, pendingTx.ToAddress = ownerHex.ChainType, ownerHex.AddressHex
+			break
+		}
+	}
+	return &pendingTx, nil
+}
+
+// pendingTxAccount returns the account the tx acts on, from the witness of its das cells, empty if none
+func pendingTxAccount(tx *types.Transaction, action common.DasAction) (accountId, account string) {
+	switch action {
+	case common.DasActionDeclareReverseRecord, common.DasActionRedeclareReverseRecord:
+		if len(tx.OutputsData) > 0 {
+			account = string(tx.OutputsData[0])
+			return common.Bytes2Hex(common.GetAccountIdByAccount(account)), account
+		}
+		return
+	}
+	for _, dataType := range []common.DataType{common.DataTypeNew, common.DataTypeOld} {
+		if mapAccount, err := witness.AccountCellDataBuilderMapFromTx(tx, dataType); err == nil {
+			// the account cell with the lowest index, the map is unordered
+			var first *witness.AccountCellDataBuilder
+			for _, v := range mapAccount {
+				if first == nil || v.Index < first.Index {
+					first = v
+				}
+			}
+			if first != nil {
+				return first.AccountId, first.Account
+			}
+		}
+		if sale, err := witness.AccountSaleCellDataBuilderFromTx(tx, dataType); err == nil && sale.Account != "" {
+			return common.Bytes2Hex(common.GetAccountIdByAccount(sale.Account)), sale.Account
+		}
+		if offer, err := witness.OfferCellDataBuilderFromTx(tx, dataType); err == nil && offer.Account != "" {
+			return common.Bytes2Hex(common.GetAccountIdByAccount(offer.Account)), offer.Account
+		}
+	}
+	// one of the sub-accounts of a batch
+	if mapSubAccount, err := witness.SubAccountBuilderMapFromTx(tx); err == nil {
+		for _, v := range mapSubAccount {
+			if v.SubAccount != nil {
+				account = v.SubAccount.Account()
+				return common.Bytes2Hex(common.GetAccountIdByAccount(account)), account
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,18 @@
+package block_parser
+
+import (
+	"github.com/dotbitHQ/das-lib/common"
+	"github.com/nervosnetwork/ckb-sdk-go/types"
+	"testing"
+)
+
+func TestPendingTxAccount(t *testing.T) {
+	tx := &types.Transaction{OutputsData: [][]byte{[]byte("tzh.bit")}}
+	accountId, account := pendingTxAccount(tx, common.DasActionDeclareReverseRecord)
+	if account != "tzh.bit" || accountId != common.Bytes2Hex(common.GetAccountIdByAccount("tzh.bit")) {
+		t.Fatal(accountId, account)
+	}
+	if accountId, account = pendingTxAccount(&types.Transaction{}, common.DasActionEditRecords); account != "" || accountId != "" {
+		t.Fatal(accountId, account)
+	}
+}
@@ -0,0 +1,239 @@
+package block_parser
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPollInterval = time.Second * 10
+	// a block is mined every ~10s, a subscription silent for longer is considered dropped
+	tipReadTimeout      = time.Minute
+	tipReconnectMin     = time.Second
+	tipReconnectMax     = time.Minute
+	tipSubscribeRequest = `{"id":1,"jsonrpc":"2.0","method":"subscribe","params":["new_tip_header"]}`
+)
+
+// tipSubscriber follows the new_tip_header subscription of the ckb node, over websocket (ws://, wss://)
+// or tcp (tcp://), and reconnects with backoff when it drops
+type tipSubscriber struct {
+	url    string
+	wakeUp chan struct{}
+
+	lock      sync.Mutex
+	tipNumber uint64
+	connected bool
+}
+
+func newTipSubscriber(rawUrl string) *tipSubscriber {
+	return &tipSubscriber{url: rawUrl, wakeUp: make(chan struct{}, 1)}
+}
+
+// tip returns the last tip pushed by the node, false if the subscription is down
+func (t *tipSubscriber) tip() (uint64, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.tipNumber, t.connected
+}
+
+func (t *tipSubscriber) setTip(number uint64) {
+	t.lock.Lock()
+	t.tipNumber, t.connected = number, true
+	t.lock.Unlock()
+	select {
+	case t.wakeUp <- struct{}{}:
+	default:
+	}
+}
+
+func (t *tipSubscriber) setDisconnected() {
+	t.lock.Lock()
+	t.connected = false
+	t.lock.Unlock()
+}
+
+func (t *tipSubscriber) run(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		delay := tipReconnectMin
+		for {
+			start := time.Now()
+			err := t.subscribe(ctx)
+			t.setDisconnected()
+			if ctx.Err() != nil {
+				return
+			}
+			log.Warn("new_tip_header subscription dropped, polling the tip:", err)
+			if time.Since(start) > tipReadTimeout {
+				delay = tipReconnectMin
+			}
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return
+			}
+			if delay *= 2; delay > tipReconnectMax {
+				delay = tipReconnectMax
+			}
+		}
+	}()
+}
+
+// tipConn is a connection carrying one json message per read
+type tipConn interface {
+	write(msg []byte) error
+	read(deadline time.Time) ([]byte, error)
+	close() error
+}
+
+type wsTipConn struct {
+	conn *websocket.Conn
+}
+
+func (w *wsTipConn) write(msg []byte) error {
+	return w.conn.WriteMessage(websocket.TextMessage, msg)
+}
+
+func (w *wsTipConn) read(deadline time.Time) ([]byte, error) {
+	_ = w.conn.SetReadDeadline(deadline)
+	_, msg, err := w.conn.ReadMessage()
+	return msg, err
+}
+
+func (w *wsTipConn) close() error {
+	return w.conn.Close()
+}
+
+// tcpTipConn is the tcp subscription of the node, with newline delimited messages
+type tcpTipConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func (c *tcpTipConn) write(msg []byte) error {
+	_, err := c.conn.Write(append(msg, '\n'))
+	return err
+}
+
+func (c *tcpTipConn) read(deadline time.Time) ([]byte, error) {
+	_ = c.conn.SetReadDeadline(deadline)
+	return c.reader.ReadBytes('\n')
+}
+
+func (c *tcpTipConn) close() error {
+	return c.conn.Close()
+}
+
+func (t *tipSubscriber) dial(ctx context.Context) (tipConn, error) {
+	u, err := url.Parse(t.url)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "ws", "wss":
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, t.url, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &wsTipConn{conn: conn}, nil
+	case "tcp":
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", u.Host)
+		if err != nil {
+			return nil, err
+		}
+		return &tcpTipConn{conn: conn, reader: bufio.NewReader(conn)}, nil
+	}
+	return nil, fmt.Errorf("unknown scheme: %s", u.Scheme)
+}
+
+type tipMessage struct {
+	Id     *int            `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Method string `json:"method"`
+	Params struct {
+		Result       string `json:"result"` // the header, as a json string
+		Subscription string `json:"subscription"`
+	} `json:"params"`
+}
+
+func (t *tipSubscriber) subscribe(ctx context.Context) error {
+	conn, err := t.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("dial err: %s", err.Error())
+	}
+	// unblock the read once cancelled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		_ = conn.close()
+	}()
+
+	if err = conn.write([]byte(tipSubscribeRequest)); err != nil {
+		return fmt.Errorf("subscribe err: %s", err.Error())
+	}
+	for {
+		data, err := conn.read(time.Now().Add(tipReadTimeout))
+		if err != nil {
+			return fmt.Errorf("read err: %s", err.Error())
+		}
+		var msg tipMessage
+		if err = json.Unmarshal(data, &msg); err != nil {
+			return fmt.Errorf("json.Unmarshal err: %s", err.Error())
+		}
+		if msg.Error != nil {
+			return fmt.Errorf("subscribe err: %d %s", msg.Error.Code, msg.Error.Message)
+		} else if msg.Id != nil {
+			log.Info("new_tip_header subscribed:", string(msg.Result))
+			continue
+		} else if msg.Method != "subscribe" {
+			continue
+		}
+		var header struct {
+			Number string `json:"number"`
+		}
+		if err = json.Unmarshal([]byte(msg.Params.Result), &header); err != nil {
+			return fmt.Errorf("header json.Unmarshal err: %s", err.Error())
+		}
+		number, err := strconv.ParseUint(strings.TrimPrefix(header.Number, "0x"), 16, 64)
+		if err != nil {
+			return fmt.Errorf("header number err: %s", err.Error())
+		}
+		t.setTip(number)
+	}
+}
+
+// waitNewTip sleeps until the node pushes a new tip or the poll interval is over
+func (b *BlockParser) waitNewTip() {
+	interval := b.pollInterval
+	if interval == 0 {
+		interval = defaultPollInterval
+	}
+	var wakeUp chan struct{}
+	if b.tipSubscriber != nil {
+		wakeUp = b.tipSubscriber.wakeUp
+	}
+	select {
+	case <-wakeUp:
+	case <-time.After(interval):
+	case <-b.ctx.Done():
+	}
+}
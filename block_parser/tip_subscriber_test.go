@@ -0,0 +1,49 @@
+package block_parser
+
+import (
+	"context"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTipSubscriber(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, _, err = conn.ReadMessage(); err != nil {
+			return
+		}
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","result":"0x0","id":1}`))
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","method":"subscribe","params":{"result":"{\"number\":\"0x5739a2\"}","subscription":"0x0"}}`))
+		// the subscription drops after the first tip
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := sync.WaitGroup{}
+	sub := newTipSubscriber("ws" + strings.TrimPrefix(srv.URL, "http"))
+	sub.run(ctx, &wg)
+
+	select {
+	case <-sub.wakeUp:
+	case <-time.After(time.Second * 5):
+		t.Fatal("no tip pushed")
+	}
+	if tip, _ := sub.tip(); tip != 5716386 {
+		t.Fatal("tip:", tip)
+	}
+	cancel()
+	wg.Wait()
+	if _, ok := sub.tip(); ok {
+		t.Fatal("still connected after cancel")
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"das_database/block_parser"
+	"das_database/config"
+	"das_database/dao"
+	"das_database/das_env"
+	"das_database/events"
+	"das_database/timer"
+	"fmt"
+	"github.com/dotbitHQ/das-lib/core"
+	"sync"
+	"time"
+)
+
+// network is a network parsed by the server, with its own das core, parser, timers and db
+type network struct {
+	cfg    config.Network
+	events config.Events
+	dasEnv *das_env.Env // nil with a single network
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	dbDao   *dao.DbDao
+	dasCore *core.DasCore
+	bp      *block_parser.BlockParser
+	closers []func()
+}
+
+// newNetworks returns the main network and the ones of networks, each with its own ctx under ctxServer.
+// The events of the ones of networks without their own events section are published to <events.topic>.<name>.
+func newNetworks() ([]*network, error) {
+	list := config.Cfg.NetworkList()
+	names := make(map[string]struct{})
+	var networks []*network
+	for i, v := range list {
+		if len(list) > 1 {
+			if v.Name == "" {
+				return nil, fmt.Errorf("network name empty")
+			} else if _, ok := names[v.Name]; ok {
+				return nil, fmt.Errorf("network name duplicated: %s", v.Name)
+			}
+			names[v.Name] = struct{}{}
+		}
+		n := network{cfg: v, events: config.Cfg.Policy(v.Name).Events}
+		if i > 0 && v.Events == nil {
+			n.events.Topic = fmt.Sprintf("%s.%s", n.events.Topic, v.Name)
+		}
+		if len(list) > 1 {
+			n.dasEnv = das_env.New(v.Name)
+		}
+		n.ctx, n.cancel = context.WithCancel(ctxServer)
+		networks = append(networks, &n)
+	}
+	return networks, nil
+}
+
+// start runs the parser, the pending tx tracker, the events relay and the timers of the network
+func (n *network) start() error {
+	var err error
+	chain := n.cfg.Chain
+
+	// db
+	if n.dbDao, err = initDbDao(n.cfg); err != nil {
+		return err
+	}
+	log.Info("db ok:", n.cfg.Name)
+
+	// ckb node, das core
+	dc, ckbClient, err := initDasCore(n.ctx, &n.wg, n.cfg, n.dasEnv)
+	if err != nil {
+		return err
+	}
+	n.dasCore = dc
+	n.dasEnv.RunAsyncDasCore(n.ctx, &n.wg, dc)
+	log.Info("contract ok:", n.cfg.Name)
+
+	// block source
+	var blockSource block_parser.BlockSource = block_parser.NewRpcBlockSource(ckbClient)
+	if replayFile := chain.ReplayFile; replayFile != "" {
+		fileSource, err := block_parser.NewFileBlockSource(replayFile, chain.ConfirmNum)
+		if err != nil {
+			return fmt.Errorf("NewFileBlockSource err: %s", err.Error())
+		}
+		n.closers = append(n.closers, func() { _ = fileSource.Close() })
+		blockSource = fileSource
+		log.Info("replay blocks from:", n.cfg.Name, replayFile)
+	}
+	if recordFile := chain.RecordFile; recordFile != "" {
+		recorder, err := block_parser.NewBlockRecorder(blockSource, recordFile)
+		if err != nil {
+			return fmt.Errorf("NewBlockRecorder err: %s", err.Error())
+		}
+		n.closers = append(n.closers, func() {
+			if err := recorder.Close(); err != nil {
+				log.Error("BlockRecorder Close err:", err.Error())
+			}
+		})
+		blockSource = recorder
+		log.Info("record blocks to:", n.cfg.Name, recordFile)
+	}
+
+	// block parser
+	if n.bp, err = newBlockParser(n.ctx, &n.wg, n.cfg, dc, n.dasEnv, n.dbDao, blockSource); err != nil {
+		return err
+	}
+	// the tx pool of the node means nothing to the replay of an archive
+	if cfgPendingTx := config.Cfg.Policy(n.cfg.Name).PendingTx; cfgPendingTx.Enable && chain.ReplayFile == "" {
+		n.bp.RunPendingTxTracker(time.Duration(cfgPendingTx.Interval) * time.Second)
+		log.Info("pending tx tracker ok:", n.cfg.Name)
+	}
+	n.bp.RunParser()
+
+	// events
+	if n.events.Enable {
+		publisher, err := events.NewPublisher(n.events)
+		if err != nil {
+			return fmt.Errorf("NewPublisher err: %s", err.Error())
+		}
+		relay := events.Relay{
+			DbDao:     n.dbDao,
+			Publisher: publisher,
+			Interval:  time.Duration(n.events.Interval) * time.Second,
+			BatchSize: n.events.BatchSize,
+			Ctx:       n.ctx,
+			Wg:        &n.wg,
+		}
+		relay.Run()
+		log.Info("events relay ok:", n.cfg.Name, n.events.Topic)
+	}
+
+	// timer
+	parserTimer := timer.ParserTimer{
+		Network:    n.cfg.Name,
+		DbDao:      n.dbDao,
+		Ctx:        n.ctx,
+		Wg:         &n.wg,
+		DasCore:    dc,
+		ConfirmNum: chain.ConfirmNum,
+	}
+	parserTimer.RunUpdateTokenPrice()
+	parserTimer.RunFixCharset()
+	parserTimer.RunVerify()
+	log.Info("parser timer ok:", n.cfg.Name)
+	return nil
+}
+
+// stop cancels the parser, timers and relay of the network, waits for them and closes its block source
+func (n *network) stop() {
+	n.cancel()
+	n.wg.Wait()
+	for i := len(n.closers) - 1; i >= 0; i-- {
+		n.closers[i]()
+	}
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"das_database/block_parser"
+	"das_database/config"
+	"das_database/dao"
+	"das_database/das_env"
+	"das_database/notify"
+	"das_database/timer"
+	"fmt"
+	"github.com/dotbitHQ/das-lib/core"
+	"github.com/nervosnetwork/ckb-sdk-go/rpc"
+	"github.com/urfave/cli/v2"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+func initDbDao(n config.Network) (*dao.DbDao, error) {
+	db, err := dao.NewGormDataBase(n.Mysql.Addr, n.Mysql.User, n.Mysql.Password, n.Mysql.DbName, n.Mysql.MaxOpenConn, n.Mysql.MaxIdleConn)
+	if err != nil {
+		return nil, fmt.Errorf("NewGormDataBase err:%s", err.Error())
+	}
+	dbDao, err := dao.Initialize(db, n.Name)
+	if err != nil {
+		return nil, fmt.Errorf("Initialize err:%s ", err.Error())
+	}
+	return dbDao, nil
+}
+
+// initDasCore connects the ckb node of the network and loads its das contracts, config cells and so scripts once, into dasEnv
+func initDasCore(ctx context.Context, wg *sync.WaitGroup, n config.Network, dasEnv *das_env.Env) (*core.DasCore, rpc.Client, error) {
+	ckbClient, err := rpc.DialWithIndexer(n.Chain.CkbUrl, n.Chain.IndexUrl)
+	if err != nil {
+		return nil, nil, fmt.Errorf("DialWithIndexer err: %s", err.Error())
+	}
+	log.Info("ckb node ok:", n.Name)
+
+	env := core.InitEnv(n.Net)
+	opts := []core.DasCoreOption{
+		core.WithClient(ckbClient),
+		core.WithDasContractArgs(env.ContractArgs),
+		core.WithDasContractCodeHash(env.ContractCodeHash),
+		core.WithDasNetType(n.Net),
+		core.WithTHQCodeHash(env.THQCodeHash),
+	}
+	dc := core.NewDasCore(ctx, wg, opts...)
+	err = dasEnv.Run(func() error {
+		dc.InitDasContract(env.MapContract)
+		if err := dc.InitDasConfigCell(); err != nil {
+			return fmt.Errorf("InitDasConfigCell err: %s", err.Error())
+		}
+		if err := dc.InitDasSoScript(); err != nil {
+			return fmt.Errorf("InitDasSoScript err: %s", err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return dc, ckbClient, nil
+}
+
+func newBlockParser(ctx context.Context, wg *sync.WaitGroup, n config.Network, dc *core.DasCore, dasEnv *das_env.Env, dbDao *dao.DbDao, blockSource block_parser.BlockSource) (*block_parser.BlockParser, error) {
+	// the tips of the node mean nothing to the replay of an archive, nor its blocks past the cursor
+	tipSubscribeUrl, provisional := n.Chain.CkbWsUrl, n.Chain.Provisional
+	if n.Chain.ReplayFile != "" {
+		tipSubscribeUrl, provisional = "", false
+	}
+	bp, err := block_parser.NewBlockParser(block_parser.ParamsBlockParser{
+		Network:            n.Name,
+		DasCore:            dc,
+		DasEnv:             dasEnv,
+		CurrentBlockNumber: n.Chain.CurrentBlockNumber,
+		DbDao:              dbDao,
+		ConcurrencyNum:     n.Chain.ConcurrencyNum,
+		FetchWorkerNum:     n.Chain.FetchWorkerNum,
+		ConfirmNum:         n.Chain.ConfirmNum,
+		BlockSource:        blockSource,
+		TipSubscribeUrl:    tipSubscribeUrl,
+		PollInterval:       time.Duration(n.Chain.PollInterval) * time.Second,
+		Provisional:        provisional,
+		Ctx:                ctx,
+		Wg:                 wg,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("NewBlockParser err: %s", err.Error())
+	}
+	return bp, nil
+}
+
+// toolContext is cancelled on SIGINT / SIGTERM, for the commands running once
+func toolContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-ch:
+			log.Warn("tool cancelled:", sig.String())
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(ch)
+	}()
+	return ctx, cancel
+}
+
+// networkFlag selects the network of the config a command acts on
+var networkFlag = &cli.StringFlag{Name: "network", Usage: "name of the network in the config, the main one by default"}
+
+// initToolNetwork reads the config and returns the network of --network
+func initToolNetwork(c *cli.Context) (config.Network, error) {
+	if err := config.InitCfg(c.String("config")); err != nil {
+		return config.Network{}, err
+	}
+	name := c.String("network")
+	for _, v := range config.Cfg.NetworkList() {
+		if name == "" || v.Name == name {
+			return v, nil
+		}
+	}
+	return config.Network{}, fmt.Errorf("unknown network: %s", name)
+}
+
+// initTool sets up the config, the db and the das core of the network for the commands which run the handlers, without the http server and timers
+func initTool(c *cli.Context, ctx context.Context, wg *sync.WaitGroup) (*block_parser.BlockParser, error) {
+	n, err := initToolNetwork(c)
+	if err != nil {
+		return nil, err
+	}
+	dbDao, err := initDbDao(n)
+	if err != nil {
+		return nil, err
+	}
+	dc, ckbClient, err := initDasCore(ctx, wg, n, nil)
+	if err != nil {
+		return nil, err
+	}
+	return newBlockParser(ctx, wg, n, dc, nil, dbDao, block_parser.NewRpcBlockSource(ckbClient))
+}
+
+func runReindex(c *cli.Context) error {
+	from, to := c.Uint64("from"), c.Uint64("to")
+	if to == 0 {
+		to = from
+	} else if to < from {
+		return fmt.Errorf("--to %d is before --from %d", to, from)
+	}
+	ctx, cancel := toolContext()
+	defer cancel()
+	bp, err := initTool(c, ctx, &sync.WaitGroup{})
+	if err != nil {
+		return err
+	}
+	if err = bp.ReindexBlocks(from, to); err != nil {
+		return fmt.Errorf("ReindexBlocks err: %s", err.Error())
+	}
+	fmt.Printf("reindexed blocks %d - %d\n", from, to)
+	return nil
+}
+
+func runParseTx(c *cli.Context) error {
+	txHash := c.Args().First()
+	if txHash == "" {
+		return fmt.Errorf("missing tx hash")
+	}
+	ctx, cancel := toolContext()
+	defer cancel()
+	bp, err := initTool(c, ctx, &sync.WaitGroup{})
+	if err != nil {
+		return err
+	}
+	action, err := bp.ParseTransaction(txHash)
+	if err != nil {
+		return fmt.Errorf("ParseTransaction err: %s", err.Error())
+	} else if action == "" {
+		fmt.Println("no handler for the transaction:", txHash)
+		return nil
+	}
+	fmt.Println("parsed:", action, txHash)
+	return nil
+}
+
+func runRepair(c *cli.Context) error {
+	account := c.Args().First()
+	if account == "" {
+		return fmt.Errorf("missing account")
+	}
+	ctx, cancel := toolContext()
+	defer cancel()
+	bp, err := initTool(c, ctx, &sync.WaitGroup{})
+	if err != nil {
+		return err
+	}
+	res, err := bp.RepairAccount(account, c.String("outpoint"), !c.Bool("apply"))
+	if err != nil {
+		return fmt.Errorf("RepairAccount err: %s", err.Error())
+	}
+	for _, v := range res.Txs {
+		fmt.Printf("replayed %d %s %s\n", v.BlockNumber, v.Action, v.TxHash)
+	}
+	for _, v := range res.Diff {
+		fmt.Printf("%s %s %s: %q -> %q\n", v.Table, v.Key, v.Field, v.Old, v.New)
+	}
+	fmt.Printf("%s %s: %d txs up to block %d, %d changes, applied: %t\n", res.Account, res.AccountId, len(res.Txs), res.BlockNumber, len(res.Diff), res.Applied)
+	return nil
+}
+
+// runMigrate creates or updates the tables of the db of --network, or of every network without it
+func runMigrate(c *cli.Context) error {
+	n, err := initToolNetwork(c)
+	if err != nil {
+		return err
+	}
+	list := []config.Network{n}
+	if c.String("network") == "" {
+		list = config.Cfg.NetworkList()
+	}
+	for _, v := range list {
+		db, err := dao.NewGormDataBase(v.Mysql.Addr, v.Mysql.User, v.Mysql.Password, v.Mysql.DbName, v.Mysql.MaxOpenConn, v.Mysql.MaxIdleConn)
+		if err != nil {
+			return fmt.Errorf("NewGormDataBase err:%s [%s]", err.Error(), v.Name)
+		}
+		if err = dao.Migrate(db); err != nil {
+			return fmt.Errorf("Migrate err: %s [%s]", err.Error(), v.Name)
+		}
+		fmt.Println("migrate ok:", v.Name, v.Mysql.DbName)
+	}
+	return nil
+}
+
+func runStatus(c *cli.Context) error {
+	n, err := initToolNetwork(c)
+	if err != nil {
+		return err
+	}
+	dbDao, err := initDbDao(n)
+	if err != nil {
+		return err
+	}
+	ckbClient, err := rpc.DialWithIndexer(n.Chain.CkbUrl, n.Chain.IndexUrl)
+	if err != nil {
+		return fmt.Errorf("DialWithIndexer err: %s", err.Error())
+	}
+	ctx, cancel := toolContext()
+	defer cancel()
+
+	block, err := dbDao.FindBlockInfo()
+	if err != nil {
+		return fmt.Errorf("FindBlockInfo err: %s", err.Error())
+	}
+	tip, err := ckbClient.GetTipBlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("GetTipBlockNumber err: %s", err.Error())
+	}
+	var lag uint64
+	if tip > block.BlockNumber {
+		lag = tip - block.BlockNumber
+	}
+	fmt.Printf("cursor: %d %s\ntip:    %d\nlag:    %d\n", block.BlockNumber, block.BlockHash, tip, lag)
+	return nil
+}
+
+func runVerify(c *cli.Context) error {
+	n, err := initToolNetwork(c)
+	if err != nil {
+		return err
+	}
+	dbDao, err := initDbDao(n)
+	if err != nil {
+		return err
+	}
+	ckbClient, err := rpc.DialWithIndexer(n.Chain.CkbUrl, n.Chain.IndexUrl)
+	if err != nil {
+		return fmt.Errorf("DialWithIndexer err: %s", err.Error())
+	}
+	ctx, cancel := toolContext()
+	defer cancel()
+
+	// the blocks kept in t_block_info must still be on the canonical chain
+	list, err := dbDao.FindBlockInfoList()
+	if err != nil {
+		return fmt.Errorf("FindBlockInfoList err: %s", err.Error())
+	}
+	mismatch := 0
+	for _, v := range list {
+		header, err := ckbClient.GetHeaderByNumber(ctx, v.BlockNumber)
+		if err != nil {
+			return fmt.Errorf("GetHeaderByNumber err: %s [%d]", err.Error(), v.BlockNumber)
+		}
+		if header.Hash.Hex() != v.BlockHash {
+			mismatch++
+			fmt.Printf("block %d: indexed %s, chain %s\n", v.BlockNumber, v.BlockHash, header.Hash.Hex())
+		}
+	}
+	fmt.Printf("verified %d blocks, %d mismatched\n", len(list), mismatch)
+	if mismatch > 0 {
+		return fmt.Errorf("%d indexed blocks are not on the canonical chain", mismatch)
+	}
+	if c.Bool("cells") {
+		return runVerifyCells(c, ctx, n, dbDao)
+	}
+	return nil
+}
+
+func runVerifyCells(c *cli.Context, ctx context.Context, n config.Network, dbDao *dao.DbDao) error {
+	if err := notify.Init(config.Cfg.Notice); err != nil {
+		return fmt.Errorf("notify Init err: %s", err.Error())
+	}
+	wg := sync.WaitGroup{}
+	dc, _, err := initDasCore(ctx, &wg, n, nil)
+	if err != nil {
+		return err
+	}
+	sampleSize := c.Int("sample")
+	if sampleSize == 0 {
+		sampleSize = config.Cfg.Policy(n.Name).Verify.SampleSize
+	}
+	parserTimer := timer.ParserTimer{Network: n.Name, DbDao: dbDao, Ctx: ctx, Wg: &wg, DasCore: dc, ConfirmNum: n.Chain.ConfirmNum}
+	res, err := parserTimer.Verify(timer.VerifyParams{
+		Full:       c.Bool("full"),
+		SampleSize: sampleSize,
+		Tables:     c.StringSlice("table"),
+	})
+	if err != nil {
+		return fmt.Errorf("Verify err: %s", err.Error())
+	}
+	for _, v := range res.Reports {
+		fmt.Printf("%s %d %s %s: indexed %q, chain %q\n", v.CheckTable, v.RowId, v.Account, v.Field, v.DbValue, v.ChainValue)
+	}
+	fmt.Printf("run %s: checked %v, %d errors, %d discrepancies\n", res.RunId, res.Checked, res.Errors, len(res.Reports))
+	if len(res.Reports) > 0 {
+		return fmt.Errorf("%d discrepancies, see t_verify_report run_id %s", len(res.Reports), res.RunId)
+	}
+	return nil
+}
@@ -2,27 +2,21 @@ package main
 
 import (
 	"context"
-	"das_database/block_parser"
 	"das_database/config"
-	"das_database/dao"
 	"das_database/http_server"
-	"das_database/timer"
+	"das_database/http_server/handle"
+	"das_database/notify"
 	"fmt"
-	"github.com/dotbitHQ/das-lib/core"
-	"github.com/nervosnetwork/ckb-sdk-go/rpc"
 	"github.com/scorpiotzh/mylog"
 	"github.com/scorpiotzh/toolib"
 	"github.com/urfave/cli/v2"
 	"os"
-	"sync"
-	"time"
 )
 
 var (
 	log               = mylog.NewLogger("main", mylog.LevelDebug)
 	exit              = make(chan struct{})
 	ctxServer, cancel = context.WithCancel(context.Background())
-	wgServer          = sync.WaitGroup{}
 )
 
 func main() {
@@ -36,6 +30,65 @@ func main() {
 			},
 		},
 		Action: runServer,
+		Commands: []*cli.Command{
+			{
+				Name:   "serve",
+				Usage:  "Run the parser, timers and http server (default)",
+				Action: runServer,
+			},
+			{
+				Name:  "reindex",
+				Usage: "Run the handlers of the txs of a block range again",
+				Flags: []cli.Flag{
+					&cli.Uint64Flag{Name: "from", Usage: "first block number", Required: true},
+					&cli.Uint64Flag{Name: "to", Usage: "last block number, the first one by default"},
+					networkFlag,
+				},
+				Action: runReindex,
+			},
+			{
+				Name:      "parse-tx",
+				Usage:     "Run the handler of a tx again, like /v1/parser/transaction",
+				ArgsUsage: "<tx hash>",
+				Flags:     []cli.Flag{networkFlag},
+				Action:    runParseTx,
+			},
+			{
+				Name:   "migrate",
+				Usage:  "Create or update the tables of the db of every network, or of --network",
+				Flags:  []cli.Flag{networkFlag},
+				Action: runMigrate,
+			},
+			{
+				Name:   "status",
+				Usage:  "Print the parser cursor, the node tip and the lag",
+				Flags:  []cli.Flag{networkFlag},
+				Action: runStatus,
+			},
+			{
+				Name:      "repair",
+				Usage:     "Rebuild the rows of an account from its cell history, like /v1/admin/account/repair",
+				ArgsUsage: "<account or account id>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "outpoint", Usage: "a cell of the account to walk its history from, the indexed one by default"},
+					&cli.BoolFlag{Name: "apply", Usage: "replace the rows of the account, otherwise only print the diff"},
+					networkFlag,
+				},
+				Action: runRepair,
+			},
+			{
+				Name:  "verify",
+				Usage: "Check the indexed block hashes against the node, and with --cells the indexed rows against their cells",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "cells", Usage: "compare the accounts, offers, sale listings and reverse records with the live cells"},
+					&cli.BoolFlag{Name: "full", Usage: "check every row instead of a sample"},
+					&cli.IntFlag{Name: "sample", Usage: "rows checked per table from a random id, verify.sample_size by default"},
+					&cli.StringSliceFlag{Name: "table", Usage: "tables to check, all by default"},
+					networkFlag,
+				},
+				Action: runVerify,
+			},
+		},
 	}
 
 	if err := app.Run(os.Args); err != nil {
@@ -56,80 +109,38 @@ func runServer(ctx *cli.Context) error {
 		return err
 	}
 
-	// db
-	cfgMysql := config.Cfg.DB.Mysql
-	db, err := dao.NewGormDataBase(cfgMysql.Addr, cfgMysql.User, cfgMysql.Password, cfgMysql.DbName, cfgMysql.MaxOpenConn, cfgMysql.MaxIdleConn)
-	if err != nil {
-		return fmt.Errorf("NewGormDataBase err:%s", err.Error())
-	}
-	dbDao, err := dao.Initialize(db)
-	if err != nil {
-		return fmt.Errorf("Initialize err:%s ", err.Error())
-	}
-	log.Info("db ok")
-
-	// ckb node
-	ckbClient, err := rpc.DialWithIndexer(config.Cfg.Chain.CkbUrl, config.Cfg.Chain.IndexUrl)
-	if err != nil {
-		return fmt.Errorf("DialWithIndexer err: %s", err.Error())
-	}
-	log.Info("ckb node ok")
-
-	// das core
-	env := core.InitEnv(config.Cfg.Server.Net)
-	opts := []core.DasCoreOption{
-		core.WithClient(ckbClient),
-		core.WithDasContractArgs(env.ContractArgs),
-		core.WithDasContractCodeHash(env.ContractCodeHash),
-		core.WithDasNetType(config.Cfg.Server.Net),
-		core.WithTHQCodeHash(env.THQCodeHash),
-	}
-	dc := core.NewDasCore(ctxServer, &wgServer, opts...)
-	dc.InitDasContract(env.MapContract)
-	if err := dc.InitDasConfigCell(); err != nil {
-		return fmt.Errorf("InitDasConfigCell err: %s", err.Error())
-	}
-	if err := dc.InitDasSoScript(); err != nil {
-		return fmt.Errorf("InitDasSoScript err: %s", err.Error())
+	// notify
+	if err := notify.Init(config.Cfg.Notice); err != nil {
+		return fmt.Errorf("notify Init err: %s", err.Error())
 	}
-	dc.RunAsyncDasContract(time.Minute * 5)   // contract outpoint
-	dc.RunAsyncDasConfigCell(time.Minute * 3) // config cell outpoint
-	dc.RunAsyncDasSoScript(time.Minute * 7)   // so
-	log.Info("contract ok")
 
-	// block parser
-	bp, err := block_parser.NewBlockParser(block_parser.ParamsBlockParser{
-		DasCore:            dc,
-		CurrentBlockNumber: config.Cfg.Chain.CurrentBlockNumber,
-		DbDao:              dbDao,
-		ConcurrencyNum:     config.Cfg.Chain.ConcurrencyNum,
-		ConfirmNum:         config.Cfg.Chain.ConfirmNum,
-		Ctx:                ctxServer,
-		Wg:                 &wgServer,
-	})
+	// networks: db, das core, block parser, events, timer
+	networks, err := newNetworks()
 	if err != nil {
-		return fmt.Errorf("NewBlockParser err: %s", err.Error())
+		return err
 	}
-	bp.RunParser()
-
-	// timer
-	parserTimer := timer.ParserTimer{
-		DbDao:   dbDao,
-		Ctx:     ctxServer,
-		Wg:      &wgServer,
-		DasCore: dc,
+	for i, n := range networks {
+		if err := n.start(); err != nil {
+			for _, started := range networks[:i+1] {
+				started.stop()
+			}
+			return fmt.Errorf("network %s start err: %s", n.cfg.Name, err.Error())
+		}
 	}
-	parserTimer.RunUpdateTokenPrice()
-	parserTimer.RunFixCharset()
-	log.Info("parser timer ok")
 
 	// http server
+	mainNetwork, otherNetworks := networks[0], make(map[string]handle.HttpHandleParams)
+	for _, n := range networks[1:] {
+		otherNetworks[n.cfg.Name] = handle.HttpHandleParams{DbDao: n.dbDao, DasCore: n.dasCore, Bp: n.bp}
+	}
 	hs, err := http_server.Initialize(http_server.HttpServerParams{
-		Address: config.Cfg.Server.HttpServerAddr,
-		DbDao:   dbDao,
-		Ctx:     ctxServer,
-		DasCore: dc,
-		Bp:      bp,
+		Address:  config.Cfg.Server.HttpServerAddr,
+		DbDao:    mainNetwork.dbDao,
+		Ctx:      ctxServer,
+		DasCore:  mainNetwork.dasCore,
+		Bp:       mainNetwork.bp,
+		Network:  mainNetwork.cfg.Name,
+		Networks: otherNetworks,
 	})
 	if err != nil {
 		return fmt.Errorf("http server Initialize err:%s", err.Error())
@@ -144,7 +155,9 @@ func runServer(ctx *cli.Context) error {
 			_ = watcher.Close()
 		}
 		cancel()
-		wgServer.Wait()
+		for _, n := range networks {
+			n.stop()
+		}
 		exit <- struct{}{}
 	})
 
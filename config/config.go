@@ -43,21 +43,104 @@ type CfgServer struct {
 		Net            common.DasNetType `json:"net" yaml:"net"`
 		HttpServerAddr string            `json:"http_server_addr" yaml:"http_server_addr"`
 		FixCharset     bool              `json:"fix_charset" yaml:"fix_charset"`
+		Network        string            `json:"network" yaml:"network"`
+		AdminToken     string            `json:"admin_token" yaml:"admin_token"`
 	} `json:"server" yaml:"server"`
-	Notice struct {
-		WebhookLarkErr string `json:"webhook_lark_err" yaml:"webhook_lark_err"`
-	} `json:"notice" yaml:"notice"`
-	Chain struct {
-		CkbUrl             string `json:"ckb_url" yaml:"ckb_url"`
-		IndexUrl           string `json:"index_url" yaml:"index_url"`
-		CurrentBlockNumber uint64 `json:"current_block_number" yaml:"current_block_number"`
-		ConfirmNum         uint64 `json:"confirm_num" yaml:"confirm_num"`
-		ConcurrencyNum     uint64 `json:"concurrency_num" yaml:"concurrency_num"`
-	} `json:"chain" yaml:"chain"`
-	DB struct {
+	Notice Notice `json:"notice" yaml:"notice"`
+	Chain  Chain  `json:"chain" yaml:"chain"`
+	DB     struct {
 		Mysql DbMysql `json:"mysql" yaml:"mysql"`
 	} `json:"db" yaml:"db"`
-	GeckoIds []string `json:"gecko_ids" yaml:"gecko_ids"`
+	ParserPolicy ParserPolicy `json:"parser_policy" yaml:"parser_policy"`
+	Health       Health       `json:"health" yaml:"health"`
+	Events       Events       `json:"events" yaml:"events"`
+	ChangeFeed   ChangeFeed   `json:"change_feed" yaml:"change_feed"`
+	Verify       Verify       `json:"verify" yaml:"verify"`
+	PendingTx    PendingTx    `json:"pending_tx" yaml:"pending_tx"`
+	GeckoIds     []string     `json:"gecko_ids" yaml:"gecko_ids"`
+	Networks     []Network    `json:"networks" yaml:"networks"`
+}
+
+// MainNetwork is the network of server.net, chain and db
+func (c *CfgServer) MainNetwork() Network {
+	return Network{Name: c.NetworkName(), Net: c.Server.Net, Chain: c.Chain, Mysql: c.DB.Mysql}
+}
+
+// NetworkList returns the networks parsed by this process, the main one first
+func (c *CfgServer) NetworkList() []Network {
+	return append([]Network{c.MainNetwork()}, c.Networks...)
+}
+
+// Policy returns the settings of the network name: the sections set in its entry of networks,
+// the ones of the main config otherwise. It is read again on every call, so that it follows the config updates.
+func (c *CfgServer) Policy(name string) Policy {
+	policy := Policy{
+		AdminToken:   c.Server.AdminToken,
+		ParserPolicy: c.ParserPolicy,
+		Health:       c.Health,
+		Events:       c.Events,
+		ChangeFeed:   c.ChangeFeed,
+		Verify:       c.Verify,
+		PendingTx:    c.PendingTx,
+	}
+	for _, v := range c.Networks {
+		if v.Name != name || name == c.NetworkName() {
+			continue
+		}
+		if v.AdminToken != "" {
+			policy.AdminToken = v.AdminToken
+		}
+		if v.ParserPolicy != nil {
+			policy.ParserPolicy = *v.ParserPolicy
+		}
+		if v.Health != nil {
+			policy.Health = *v.Health
+		}
+		if v.Events != nil {
+			policy.Events = *v.Events
+		}
+		if v.ChangeFeed != nil {
+			policy.ChangeFeed = *v.ChangeFeed
+		}
+		if v.Verify != nil {
+			policy.Verify = *v.Verify
+		}
+		if v.PendingTx != nil {
+			policy.PendingTx = *v.PendingTx
+		}
+	}
+	return policy
+}
+
+// NetworkName is the name of the network of this process in the /v1/{network}/... routes,
+// server.network or the one of server.net
+func (c *CfgServer) NetworkName() string {
+	if c.Server.Network != "" {
+		return c.Server.Network
+	}
+	switch c.Server.Net {
+	case common.DasNetTypeMainNet:
+		return "mainnet"
+	case common.DasNetTypeTestnet2:
+		return "testnet2"
+	case common.DasNetTypeTestnet3:
+		return "testnet3"
+	}
+	return ""
+}
+
+type Chain struct {
+	CkbUrl             string `json:"ckb_url" yaml:"ckb_url"`
+	IndexUrl           string `json:"index_url" yaml:"index_url"`
+	CurrentBlockNumber uint64 `json:"current_block_number" yaml:"current_block_number"`
+	ConfirmNum         uint64 `json:"confirm_num" yaml:"confirm_num"`
+	ConcurrencyNum     uint64 `json:"concurrency_num" yaml:"concurrency_num"`
+	FetchWorkerNum     uint64 `json:"fetch_worker_num" yaml:"fetch_worker_num"`
+	ReplayFile         string `json:"replay_file" yaml:"replay_file"`
+	RecordFile         string `json:"record_file" yaml:"record_file"`
+	CkbWsUrl           string `json:"ckb_ws_url" yaml:"ckb_ws_url"`
+	PollInterval       uint64 `json:"poll_interval" yaml:"poll_interval"`
+	Provisional        bool   `json:"provisional" yaml:"provisional"`
 }
 
 type DbMysql struct {
@@ -68,3 +151,93 @@ type DbMysql struct {
 	MaxOpenConn int    `json:"max_open_conn" yaml:"max_open_conn"`
 	MaxIdleConn int    `json:"max_idle_conn" yaml:"max_idle_conn"`
 }
+
+type Notice struct {
+	WebhookLarkErr string     `json:"webhook_lark_err" yaml:"webhook_lark_err"`
+	DedupWindow    uint64     `json:"dedup_window" yaml:"dedup_window"`
+	RateLimit      int        `json:"rate_limit" yaml:"rate_limit"`
+	Notifiers      []Notifier `json:"notifiers" yaml:"notifiers"`
+}
+
+type Notifier struct {
+	Type        string   `json:"type" yaml:"type"`
+	MinSeverity string   `json:"min_severity" yaml:"min_severity"`
+	Template    string   `json:"template" yaml:"template"`
+	RateLimit   int      `json:"rate_limit" yaml:"rate_limit"`
+	Url         string   `json:"url" yaml:"url"`
+	BotToken    string   `json:"bot_token" yaml:"bot_token"`
+	ChatId      string   `json:"chat_id" yaml:"chat_id"`
+	SmtpAddr    string   `json:"smtp_addr" yaml:"smtp_addr"`
+	Username    string   `json:"username" yaml:"username"`
+	Password    string   `json:"password" yaml:"password"`
+	From        string   `json:"from" yaml:"from"`
+	To          []string `json:"to" yaml:"to"`
+}
+
+type ParserPolicy struct {
+	MaxRetry      int               `json:"max_retry" yaml:"max_retry"`
+	BackoffMin    uint64            `json:"backoff_min" yaml:"backoff_min"`
+	BackoffMax    uint64            `json:"backoff_max" yaml:"backoff_max"`
+	StuckAlert    uint64            `json:"stuck_alert" yaml:"stuck_alert"`
+	DefaultPolicy string            `json:"default_policy" yaml:"default_policy"`
+	Actions       map[string]string `json:"actions" yaml:"actions"`
+}
+
+type Health struct {
+	MaxBlockLag   uint64 `json:"max_block_lag" yaml:"max_block_lag"`
+	MaxErrRetry   int    `json:"max_err_retry" yaml:"max_err_retry"`
+	MaxBlockDelay uint64 `json:"max_block_delay" yaml:"max_block_delay"`
+}
+
+type Events struct {
+	Enable    bool   `json:"enable" yaml:"enable"`
+	Publisher string `json:"publisher" yaml:"publisher"`
+	Url       string `json:"url" yaml:"url"`
+	Topic     string `json:"topic" yaml:"topic"`
+	Interval  uint64 `json:"interval" yaml:"interval"`
+	BatchSize int    `json:"batch_size" yaml:"batch_size"`
+}
+
+type ChangeFeed struct {
+	Enable bool `json:"enable" yaml:"enable"`
+}
+
+type Verify struct {
+	Enable     bool   `json:"enable" yaml:"enable"`
+	Interval   uint64 `json:"interval" yaml:"interval"`
+	Full       bool   `json:"full" yaml:"full"`
+	SampleSize int    `json:"sample_size" yaml:"sample_size"`
+}
+
+type PendingTx struct {
+	Enable   bool   `json:"enable" yaml:"enable"`
+	Interval uint64 `json:"interval" yaml:"interval"`
+}
+
+// Network is another network (or deployment) parsed by this process, with its own das core, parser, timers and db,
+// served under /v1/{name}/...
+// The sections left out take the ones of the main config, see Policy.
+type Network struct {
+	Name         string            `json:"name" yaml:"name"`
+	Net          common.DasNetType `json:"net" yaml:"net"`
+	Chain        Chain             `json:"chain" yaml:"chain"`
+	Mysql        DbMysql           `json:"mysql" yaml:"mysql"`
+	AdminToken   string            `json:"admin_token" yaml:"admin_token"`
+	ParserPolicy *ParserPolicy     `json:"parser_policy" yaml:"parser_policy"`
+	Health       *Health           `json:"health" yaml:"health"`
+	Events       *Events           `json:"events" yaml:"events"`
+	ChangeFeed   *ChangeFeed       `json:"change_feed" yaml:"change_feed"`
+	Verify       *Verify           `json:"verify" yaml:"verify"`
+	PendingTx    *PendingTx        `json:"pending_tx" yaml:"pending_tx"`
+}
+
+// Policy is the part of the config a network can set for itself
+type Policy struct {
+	AdminToken   string
+	ParserPolicy ParserPolicy
+	Health       Health
+	Events       Events
+	ChangeFeed   ChangeFeed
+	Verify       Verify
+	PendingTx    PendingTx
+}
@@ -1,6 +1,7 @@
 package dao
 
 import (
+	"context"
 	"das_database/config"
 	"fmt"
 	"gorm.io/driver/mysql"
@@ -9,7 +10,9 @@ import (
 )
 
 type DbDao struct {
-	db *gorm.DB
+	db         *gorm.DB
+	network    string // label of the metrics, name of the network of the config
+	undoModels undoModelMap
 }
 
 func NewGormDataBase(addr, user, password, dbName string, maxOpenConn, maxIdleConn int) (*gorm.DB, error) {
@@ -32,11 +35,20 @@ func NewGormDataBase(addr, user, password, dbName string, maxOpenConn, maxIdleCo
 	return db, nil
 }
 
-func Initialize(db *gorm.DB) (*DbDao, error) {
+// Transaction runs fn with a DbDao bound to a single db transaction,
+// the transactions opened by its methods become savepoints of it
+func (d *DbDao) Transaction(fn func(txDao *DbDao) error) error {
+	return d.timedTransaction(callerFuncName(2), func(tx *gorm.DB) error {
+		return fn(&DbDao{db: tx, network: d.network, undoModels: d.undoModels})
+	})
+}
+
+// Migrate creates or updates the tables, without registering the callbacks of the DbDao
+func Migrate(db *gorm.DB) error {
 	// AutoMigrate will create tables, missing foreign keys, constraints, columns and indexes.
 	// It will change existing column’s type if its size, precision, nullable changed.
 	// It WON’T delete unused columns to protect your data.
-	if err := db.AutoMigrate(
+	return db.AutoMigrate(
 		&TableAccountInfo{},
 		&TableBlockInfo{},
 		&TableIncomeCellInfo{},
@@ -51,7 +63,40 @@ func Initialize(db *gorm.DB) (*DbDao, error) {
 		&TableTransactionInfo{},
 		&TableCustomScriptInfo{},
 		&TableTradeHistoryInfo{},
-	); err != nil {
+		&TableBlockUndoInfo{},
+		&TableDeadLetterInfo{},
+		&TableAccountHistory{},
+		&TableRecordsHistory{},
+		&TableCrossChainInfo{},
+		&TableEventOutbox{},
+		&TableChangeInfo{},
+		&TableVerifyReport{},
+		&TablePendingTx{},
+		&TableProvisionalChange{},
+	)
+}
+
+func Initialize(db *gorm.DB, network string) (*DbDao, error) {
+	if err := Migrate(db); err != nil {
+		return nil, err
+	}
+	if err := seedAccountHistory(db); err != nil {
+		return nil, fmt.Errorf("seedAccountHistory err: %s", err.Error())
+	}
+	undoModels := newUndoModelMap()
+	if err := registerUndoCallbacks(db, undoModels); err != nil {
+		return nil, err
+	}
+	if err := registerRowChangeCallbacks(db); err != nil {
+		return nil, err
+	}
+	if err := registerAccountHistoryCallbacks(db); err != nil {
+		return nil, err
+	}
+	if err := registerRecordsHistoryCallbacks(db); err != nil {
+		return nil, err
+	}
+	if err := registerMetricsCallbacks(db, network); err != nil {
 		return nil, err
 	}
 
@@ -69,7 +114,7 @@ func Initialize(db *gorm.DB) (*DbDao, error) {
 		}
 	}
 
-	return &DbDao{db: db}, nil
+	return &DbDao{db: db, network: network, undoModels: undoModels}, nil
 }
 
 var geckoIds = map[string]TableTokenPriceInfo{
@@ -137,3 +182,11 @@ var geckoIds = map[string]TableTokenPriceInfo{
 		Logo:      "https://app.did.id/images/components/polygon.svg",
 	},
 }
+
+func (d *DbDao) Ping(ctx context.Context) error {
+	sqlDB, err := d.db.DB()
+	if err != nil {
+		return fmt.Errorf("gorm db :%v", err)
+	}
+	return sqlDB.PingContext(ctx)
+}
@@ -0,0 +1,293 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"github.com/dotbitHQ/das-lib/common"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+	"time"
+)
+
+type TableAccountHistory struct {
+	Id                 uint64                `json:"id" gorm:"column:id;primaryKey;type:bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT ''"`
+	BlockNumber        uint64                `json:"block_number" gorm:"column:block_number;index:k_ai_bn,priority:2;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT ''"`
+	BlockTimestamp     uint64                `json:"block_timestamp" gorm:"column:block_timestamp;index:k_ai_bt,priority:2;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT ''"`
+	AccountId          string                `json:"account_id" gorm:"column:account_id;index:k_ai_bn,priority:1;index:k_ai_bt,priority:1;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'hash of account'"`
+	Account            string                `json:"account" gorm:"column:account;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	TxHash             string                `json:"tx_hash" gorm:"column:tx_hash;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	Action             string                `json:"action" gorm:"column:action;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	Operation          string                `json:"operation" gorm:"column:operation;type:varchar(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'create, update, delete or seed'"`
+	Outpoint           string                `json:"outpoint" gorm:"column:outpoint;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	OwnerChainType     common.ChainType      `json:"owner_chain_type" gorm:"column:owner_chain_type;type:smallint(6) NOT NULL DEFAULT '0' COMMENT ''"`
+	Owner              string                `json:"owner" gorm:"column:owner;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'owner address'"`
+	OwnerAlgorithmId   common.DasAlgorithmId `json:"owner_algorithm_id" gorm:"column:owner_algorithm_id;type:smallint(6) NOT NULL DEFAULT '0' COMMENT ''"`
+	ManagerChainType   common.ChainType      `json:"manager_chain_type" gorm:"column:manager_chain_type;type:smallint(6) NOT NULL DEFAULT '0' COMMENT ''"`
+	Manager            string                `json:"manager" gorm:"column:manager;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'manager address'"`
+	ManagerAlgorithmId common.DasAlgorithmId `json:"manager_algorithm_id" gorm:"column:manager_algorithm_id;type:smallint(6) NOT NULL DEFAULT '0' COMMENT ''"`
+	Status             uint8                 `json:"status" gorm:"column:status;type:smallint(6) NOT NULL DEFAULT '0' COMMENT ''"`
+	RegisteredAt       uint64                `json:"registered_at" gorm:"column:registered_at;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT ''"`
+	ExpiredAt          uint64                `json:"expired_at" gorm:"column:expired_at;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT ''"`
+	CreatedAt          time.Time             `json:"created_at" gorm:"column:created_at;type:timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT ''"`
+	UpdatedAt          time.Time             `json:"updated_at" gorm:"column:updated_at;type:timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT ''"`
+}
+
+const (
+	TableNameAccountHistory = "t_account_history"
+
+	AccountOperationCreate = "create"
+	AccountOperationUpdate = "update"
+	AccountOperationDelete = "delete"
+	AccountOperationSeed   = "seed" // the state of an account indexed before t_account_history existed
+)
+
+func (t *TableAccountHistory) TableName() string {
+	return TableNameAccountHistory
+}
+
+type ctxKeyTxInfo struct{}
+
+type txInfo struct {
+	action         string
+	txHash         string
+	blockTimestamp uint64
+}
+
+// WithAction returns a DbDao whose writes to t_account_info and t_records_info are recorded
+// in t_account_history and t_records_history as done by the tx, it is meant to be used together with WithBlockNumber
+func (d *DbDao) WithAction(action, txHash string, blockTimestamp uint64) *DbDao {
+	ctx := context.WithValue(d.db.Statement.Context, ctxKeyTxInfo{}, txInfo{
+		action:         action,
+		txHash:         txHash,
+		blockTimestamp: blockTimestamp,
+	})
+	return &DbDao{db: d.db.WithContext(ctx), network: d.network, undoModels: d.undoModels}
+}
+
+func (d *DbDao) FindAccountHistory(accountId string, limit, offset int) (list []TableAccountHistory, err error) {
+	err = d.db.Where("account_id = ?", accountId).
+		Order("block_number DESC, id DESC").Limit(limit).Offset(offset).Find(&list).Error
+	return
+}
+
+func (d *DbDao) GetAccountHistoryCount(accountId string) (count int64, err error) {
+	err = d.db.Model(TableAccountHistory{}).Where("account_id = ?", accountId).Count(&count).Error
+	return
+}
+
+// FindAccountHistoryAtBlock returns the latest version of the account written at or before the block
+func (d *DbDao) FindAccountHistoryAtBlock(accountId string, blockNumber uint64) (history TableAccountHistory, err error) {
+	err = d.db.Where("account_id = ? AND block_number <= ?", accountId, blockNumber).
+		Order("block_number DESC, id DESC").Limit(1).Find(&history).Error
+	return
+}
+
+// FindAccountHistoryAtTimestamp returns the latest version of the account written at or before the block timestamp
+func (d *DbDao) FindAccountHistoryAtTimestamp(accountId string, timestamp uint64) (history TableAccountHistory, err error) {
+	err = d.db.Where("account_id = ? AND block_timestamp <= ?", accountId, timestamp).
+		Order("block_timestamp DESC, id DESC").Limit(1).Find(&history).Error
+	return
+}
+
+// registerAccountHistoryCallbacks appends the new version of every t_account_info row written by a block,
+// see WithBlockNumber, to t_account_history inside the same transaction as the write.
+func registerAccountHistoryCallbacks(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register("das:account_history_before_create", accountHistoryBeforeCreate); err != nil {
+		return err
+	}
+	if err := db.Callback().Create().After("gorm:create").Register("das:account_history_after_create", accountHistoryAfterCreate); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("das:account_history_before_update", accountHistoryBeforeWhere); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:update").Register("das:account_history_after_update", accountHistoryAfterUpdate); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("gorm:delete").Register("das:account_history_before_delete", accountHistoryBeforeWhere); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().After("gorm:delete").Register("das:account_history_after_delete", accountHistoryAfterDelete); err != nil {
+		return err
+	}
+	return nil
+}
+
+const (
+	accountHistoryInstanceKeyRows     = "das:account_history_rows"
+	accountHistoryInstanceKeyExisting = "das:account_history_existing"
+)
+
+func accountHistoryBlockNumber(db *gorm.DB) (uint64, bool) {
+	if db.Error != nil || db.DryRun || db.Statement.Context == nil || db.Statement.Table != TableNameAccountInfo {
+		return 0, false
+	}
+	blockNumber, ok := db.Statement.Context.Value(ctxKeyBlockNumber{}).(uint64)
+	return blockNumber, ok
+}
+
+// remember the rows matched by the update / delete conditions
+func accountHistoryBeforeWhere(db *gorm.DB) {
+	if _, ok := accountHistoryBlockNumber(db); !ok {
+		return
+	}
+	where, ok := db.Statement.Clauses["WHERE"]
+	if !ok {
+		return
+	}
+	var rows []TableAccountInfo
+	if err := db.Session(&gorm.Session{NewDB: true}).Clauses(where.Expression).Find(&rows).Error; err != nil {
+		_ = db.AddError(fmt.Errorf("account history find err: %s", err.Error()))
+		return
+	}
+	db.InstanceSet(accountHistoryInstanceKeyRows, rows)
+}
+
+func accountHistoryCreateIds(db *gorm.DB) (accountIds []string) {
+	for _, v := range undoReflectValues(db.Statement.ReflectValue) {
+		if accountInfo, ok := v.Addr().Interface().(*TableAccountInfo); ok {
+			accountIds = append(accountIds, accountInfo.AccountId)
+		}
+	}
+	return
+}
+
+// remember the accounts already indexed before an upsert, their new versions are updates
+func accountHistoryBeforeCreate(db *gorm.DB) {
+	if _, ok := accountHistoryBlockNumber(db); !ok {
+		return
+	}
+	if _, ok := db.Statement.Clauses[clause.OnConflict{}.Name()]; !ok {
+		return
+	}
+	accountIds := accountHistoryCreateIds(db)
+	if len(accountIds) == 0 {
+		return
+	}
+	var existing []string
+	if err := db.Session(&gorm.Session{NewDB: true}).Model(&TableAccountInfo{}).
+		Where("account_id IN ?", accountIds).Pluck("account_id", &existing).Error; err != nil {
+		_ = db.AddError(fmt.Errorf("account history find err: %s", err.Error()))
+		return
+	}
+	db.InstanceSet(accountHistoryInstanceKeyExisting, existing)
+}
+
+func accountHistoryAfterCreate(db *gorm.DB) {
+	blockNumber, ok := accountHistoryBlockNumber(db)
+	if !ok {
+		return
+	}
+	accountIds := accountHistoryCreateIds(db)
+	value, ok := db.InstanceGet(accountHistoryInstanceKeyExisting)
+	if !ok {
+		createAccountHistory(db, blockNumber, AccountOperationCreate, accountIds)
+		return
+	}
+	existing := make(map[string]struct{})
+	for _, v := range value.([]string) {
+		existing[v] = struct{}{}
+	}
+	var created, updated []string
+	for _, v := range accountIds {
+		if _, ok := existing[v]; !ok {
+			created = append(created, v)
+		} else if onConflict, _ := db.Statement.Clauses[clause.OnConflict{}.Name()].Expression.(clause.OnConflict); !onConflict.DoNothing {
+			updated = append(updated, v)
+		}
+	}
+	createAccountHistory(db, blockNumber, AccountOperationCreate, created)
+	createAccountHistory(db, blockNumber, AccountOperationUpdate, updated)
+}
+
+func accountHistoryAfterUpdate(db *gorm.DB) {
+	blockNumber, ok := accountHistoryBlockNumber(db)
+	if !ok {
+		return
+	}
+	value, ok := db.InstanceGet(accountHistoryInstanceKeyRows)
+	if !ok {
+		return
+	}
+	var accountIds []string
+	for _, v := range value.([]TableAccountInfo) {
+		accountIds = append(accountIds, v.AccountId)
+	}
+	createAccountHistory(db, blockNumber, AccountOperationUpdate, accountIds)
+}
+
+func accountHistoryAfterDelete(db *gorm.DB) {
+	blockNumber, ok := accountHistoryBlockNumber(db)
+	if !ok {
+		return
+	}
+	value, ok := db.InstanceGet(accountHistoryInstanceKeyRows)
+	if !ok {
+		return
+	}
+	insertAccountHistory(db, blockNumber, AccountOperationDelete, value.([]TableAccountInfo))
+}
+
+// createAccountHistory reads back the rows as written and appends them
+func createAccountHistory(db *gorm.DB, blockNumber uint64, operation string, accountIds []string) {
+	if len(accountIds) == 0 {
+		return
+	}
+	var rows []TableAccountInfo
+	if err := db.Session(&gorm.Session{NewDB: true}).Where("account_id IN ?", accountIds).Find(&rows).Error; err != nil {
+		_ = db.AddError(fmt.Errorf("account history find err: %s", err.Error()))
+		return
+	}
+	insertAccountHistory(db, blockNumber, operation, rows)
+}
+
+func insertAccountHistory(db *gorm.DB, blockNumber uint64, operation string, rows []TableAccountInfo) {
+	if len(rows) == 0 {
+		return
+	}
+	info, _ := db.Statement.Context.Value(ctxKeyTxInfo{}).(txInfo)
+	list := make([]TableAccountHistory, 0, len(rows))
+	for _, v := range rows {
+		txHash := info.txHash
+		if txHash == "" {
+			// outpoint is {tx hash}-{index}
+			txHash = strings.Split(v.Outpoint, "-")[0]
+		}
+		list = append(list, TableAccountHistory{
+			BlockNumber:        blockNumber,
+			BlockTimestamp:     info.blockTimestamp,
+			AccountId:          v.AccountId,
+			Account:            v.Account,
+			TxHash:             txHash,
+			Action:             info.action,
+			Operation:          operation,
+			Outpoint:           v.Outpoint,
+			OwnerChainType:     v.OwnerChainType,
+			Owner:              v.Owner,
+			OwnerAlgorithmId:   v.OwnerAlgorithmId,
+			ManagerChainType:   v.ManagerChainType,
+			Manager:            v.Manager,
+			ManagerAlgorithmId: v.ManagerAlgorithmId,
+			Status:             v.Status,
+			RegisteredAt:       v.RegisteredAt,
+			ExpiredAt:          v.ExpiredAt,
+		})
+	}
+	if err := db.Session(&gorm.Session{NewDB: true}).Create(&list).Error; err != nil {
+		_ = db.AddError(fmt.Errorf("account history create err: %s", err.Error()))
+	}
+}
+
+// seedAccountHistory appends a seed version for every account without any, i.e. the accounts indexed before
+// t_account_history was added, it inserts nothing once they are seeded. The block timestamp of a seed is the one
+// of the tx of its outpoint in t_transaction_info, 0 if not indexed.
+func seedAccountHistory(db *gorm.DB) error {
+	sql := "INSERT INTO " + TableNameAccountHistory + ` (block_number, block_timestamp, account_id, account, tx_hash, action, operation,
+outpoint, owner_chain_type, owner, owner_algorithm_id, manager_chain_type, manager, manager_algorithm_id, status, registered_at, expired_at)
+SELECT a.block_number, IFNULL((SELECT MAX(t.block_timestamp) FROM ` + TableNameTransactionInfo + ` t WHERE t.outpoint = a.outpoint), 0),
+a.account_id, a.account, SUBSTRING_INDEX(a.outpoint, '-', 1), '', ?, a.outpoint, a.owner_chain_type, a.owner, a.owner_algorithm_id,
+a.manager_chain_type, a.manager, a.manager_algorithm_id, a.status, a.registered_at, a.expired_at
+FROM ` + TableNameAccountInfo + ` a WHERE NOT EXISTS (SELECT 1 FROM ` + TableNameAccountHistory + ` h WHERE h.account_id = a.account_id)`
+	return db.Exec(sql, AccountOperationSeed).Error
+}
@@ -52,7 +52,7 @@ func (t *TableAccountInfo) TableName() string {
 }
 
 func (d *DbDao) EditManager(accountInfo TableAccountInfo, transactionInfo TableTransactionInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if err := tx.Select("block_number", "outpoint", "manager_chain_type", "manager", "manager_algorithm_id").
 			Where("account_id = ?", accountInfo.AccountId).Updates(accountInfo).Error; err != nil {
 			return err
@@ -72,7 +72,7 @@ func (d *DbDao) EditManager(accountInfo TableAccountInfo, transactionInfo TableT
 }
 
 func (d *DbDao) TransferAccount(accountInfo TableAccountInfo, transactionInfo TableTransactionInfo, recordsInfos []TableRecordsInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if err := tx.Select("block_number", "outpoint", "owner_chain_type", "owner", "owner_algorithm_id", "manager_chain_type", "manager", "manager_algorithm_id").
 			Where("account_id = ?", accountInfo.AccountId).
 			Updates(accountInfo).Error; err != nil {
@@ -103,7 +103,7 @@ func (d *DbDao) TransferAccount(accountInfo TableAccountInfo, transactionInfo Ta
 }
 
 func (d *DbDao) ConfirmProposal(incomeCellInfos []TableIncomeCellInfo, accountInfos []TableAccountInfo, transactionInfos []TableTransactionInfo, rebateInfos []TableRebateInfo, records []TableRecordsInfo, recordAccountIds []string) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if len(incomeCellInfos) > 0 {
 			if err := tx.Clauses(clause.OnConflict{
 				DoUpdates: clause.AssignmentColumns([]string{
@@ -166,7 +166,7 @@ func (d *DbDao) ConfirmProposal(incomeCellInfos []TableIncomeCellInfo, accountIn
 }
 
 func (d *DbDao) EnableSubAccount(accountInfo TableAccountInfo, transactionInfo TableTransactionInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if err := tx.Select("block_number", "outpoint", "enable_sub_account", "renew_sub_account_price").
 			Where("account_id = ?", accountInfo.AccountId).
 			Updates(accountInfo).Error; err != nil {
@@ -187,7 +187,7 @@ func (d *DbDao) EnableSubAccount(accountInfo TableAccountInfo, transactionInfo T
 }
 
 func (d *DbDao) ForceRecoverAccountStatus(oldStatus uint8, accountInfo TableAccountInfo, transactionInfo TableTransactionInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if oldStatus == 1 {
 			if err := tx.Where("account_id=?", transactionInfo.AccountId).Delete(&TableTradeInfo{}).Error; err != nil {
 				return err
@@ -219,7 +219,7 @@ func (d *DbDao) GetAccountInfoByParentAccountId(parentAccountId string) (account
 }
 
 func (d *DbDao) RecycleExpiredAccount(accountInfo TableAccountInfo, transactionInfo TableTransactionInfo, accountId string, enableSubAccount uint8) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if err := tx.Select("block_number", "outpoint").
 			Where("account_id=?", accountInfo.AccountId).
 			Updates(accountInfo).Error; err != nil {
@@ -261,8 +261,8 @@ func (d *DbDao) RecycleExpiredAccount(accountInfo TableAccountInfo, transactionI
 	})
 }
 
-func (d *DbDao) AccountCrossChain(accountInfo TableAccountInfo, transactionInfo TableTransactionInfo, isTrans bool) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+func (d *DbDao) AccountCrossChain(accountInfo TableAccountInfo, transactionInfo TableTransactionInfo, crossChainInfo TableCrossChainInfo, isTrans bool) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if err := tx.Select("block_number", "outpoint",
 			"owner_chain_type", "owner", "owner_algorithm_id", "manager_chain_type", "manager", "manager_algorithm_id", "status").
 			Where("account_id = ?", accountInfo.AccountId).
@@ -285,6 +285,14 @@ func (d *DbDao) AccountCrossChain(accountInfo TableAccountInfo, transactionInfo
 			}
 		}
 
+		if crossChainInfo.Status == CrossChainStatusLocked {
+			if err := createCrossChainLock(tx, crossChainInfo); err != nil {
+				return err
+			}
+		} else if err := updateCrossChainUnlock(tx, crossChainInfo); err != nil {
+			return err
+		}
+
 		return nil
 	})
 }
@@ -299,7 +307,7 @@ func (d *DbDao) UpdateAccountCharsetNum(accCharset map[string]uint64) error {
 		return nil
 	}
 
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		for k, v := range accCharset {
 			if err := tx.Model(TableAccountInfo{}).
 				Where("account_id=? AND charset_num=0", k).
@@ -312,3 +320,30 @@ func (d *DbDao) UpdateAccountCharsetNum(accCharset map[string]uint64) error {
 		return nil
 	})
 }
+
+func (d *DbDao) FindAccountInfoByAccountId(accountId string) (accountInfo TableAccountInfo, err error) {
+	err = d.db.Where("account_id = ?", accountId).Limit(1).Find(&accountInfo).Error
+	return
+}
+
+func (d *DbDao) FindAccountListByOwner(chainType common.ChainType, owner string, limit, offset int) (list []TableAccountInfo, err error) {
+	err = d.db.Where("owner_chain_type = ? AND owner = ?", chainType, owner).
+		Order("id").Limit(limit).Offset(offset).Find(&list).Error
+	return
+}
+
+func (d *DbDao) GetAccountCountByOwner(chainType common.ChainType, owner string) (count int64, err error) {
+	err = d.db.Model(TableAccountInfo{}).Where("owner_chain_type = ? AND owner = ?", chainType, owner).Count(&count).Error
+	return
+}
+
+func (d *DbDao) FindAccountListByManager(chainType common.ChainType, manager string, limit, offset int) (list []TableAccountInfo, err error) {
+	err = d.db.Where("manager_chain_type = ? AND manager = ?", chainType, manager).
+		Order("id").Limit(limit).Offset(offset).Find(&list).Error
+	return
+}
+
+func (d *DbDao) GetAccountCountByManager(chainType common.ChainType, manager string) (count int64, err error) {
+	err = d.db.Model(TableAccountInfo{}).Where("manager_chain_type = ? AND manager = ?", chainType, manager).Count(&count).Error
+	return
+}
@@ -0,0 +1,123 @@
+package dao
+
+import (
+	"encoding/json"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AccountState is what the parser indexed for one account, replaced as a whole by a repair
+type AccountState struct {
+	AccountInfo TableAccountInfo // Id is 0 if the account is not indexed
+	Records     []TableRecordsInfo
+	Trades      []TableTradeInfo
+	Offers      []TableOfferInfo
+	Smts        []TableSmtInfo
+}
+
+func findAccountState(db *gorm.DB, accountId string) (state AccountState, err error) {
+	if err = db.Where("account_id = ?", accountId).Limit(1).Find(&state.AccountInfo).Error; err != nil {
+		return
+	}
+	if err = db.Where("account_id = ?", accountId).Order("id").Find(&state.Records).Error; err != nil {
+		return
+	}
+	if err = db.Where("account_id = ?", accountId).Order("id").Find(&state.Trades).Error; err != nil {
+		return
+	}
+	if err = db.Where("account_id = ?", accountId).Order("id").Find(&state.Offers).Error; err != nil {
+		return
+	}
+	err = db.Where("account_id = ?", accountId).Order("id").Find(&state.Smts).Error
+	return
+}
+
+func (d *DbDao) FindAccountState(accountId string) (AccountState, error) {
+	return findAccountState(d.db, accountId)
+}
+
+// ClearAccountState deletes the rows of the account, before its txs are replayed
+func (d *DbDao) ClearAccountState(accountId string) error {
+	return d.transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&TableAccountInfo{}, &TableRecordsInfo{}, &TableTradeInfo{}, &TableSmtInfo{}} {
+			if err := tx.Where("account_id = ?", accountId).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ReplaceAccountState swaps the rows of the account from old to state, the current rows are locked
+// until the end of the transaction, it fails if the parser changed any of them since old was read
+func (d *DbDao) ReplaceAccountState(accountId string, old, state AccountState) error {
+	return d.transaction(func(tx *gorm.DB) error {
+		current, err := findAccountState(tx.Clauses(clause.Locking{Strength: "UPDATE"}).Session(&gorm.Session{}), accountId)
+		if err != nil {
+			return err
+		}
+		if equal, err := accountStateEqual(current, old); err != nil {
+			return err
+		} else if !equal {
+			return fmt.Errorf("account changed by the parser, try again")
+		}
+
+		for _, model := range []interface{}{&TableAccountInfo{}, &TableRecordsInfo{}, &TableTradeInfo{}, &TableOfferInfo{}, &TableSmtInfo{}} {
+			if err := tx.Where("account_id = ?", accountId).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if state.AccountInfo.AccountId != "" {
+			state.AccountInfo.Id = old.AccountInfo.Id
+			if err := tx.Create(&state.AccountInfo).Error; err != nil {
+				return err
+			}
+		}
+		for i := range state.Records {
+			state.Records[i].Id = 0
+		}
+		for i := range state.Trades {
+			state.Trades[i].Id = 0
+		}
+		for i := range state.Offers {
+			state.Offers[i].Id = 0
+		}
+		for i := range state.Smts {
+			state.Smts[i].Id = 0
+		}
+		if len(state.Records) > 0 {
+			if err := tx.Create(&state.Records).Error; err != nil {
+				return err
+			}
+		}
+		if len(state.Trades) > 0 {
+			if err := tx.Create(&state.Trades).Error; err != nil {
+				return err
+			}
+		}
+		if len(state.Offers) > 0 {
+			if err := tx.Create(&state.Offers).Error; err != nil {
+				return err
+			}
+		}
+		if len(state.Smts) > 0 {
+			if err := tx.Create(&state.Smts).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func accountStateEqual(a, b AccountState) (bool, error) {
+	dataA, err := json.Marshal(a)
+	if err != nil {
+		return false, err
+	}
+	dataB, err := json.Marshal(b)
+	if err != nil {
+		return false, err
+	}
+	return string(dataA) == string(dataB), nil
+}
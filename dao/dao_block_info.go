@@ -45,3 +45,8 @@ func (d *DbDao) FindBlockInfoByBlockNumber(blockNumber uint64) (blockInfo TableB
 	err = d.db.Where("block_number = ?", blockNumber).Limit(1).Find(&blockInfo).Error
 	return
 }
+
+func (d *DbDao) FindBlockInfoList() (list []TableBlockInfo, err error) {
+	err = d.db.Order("block_number").Find(&list).Error
+	return
+}
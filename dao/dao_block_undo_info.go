@@ -0,0 +1,342 @@
+package dao
+
+import (
+	"context"
+	"das_database/config"
+	"encoding/json"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+type TableBlockUndoInfo struct {
+	Id          uint64    `json:"id" gorm:"column:id;primaryKey;type:bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT ''"`
+	BlockNumber uint64    `json:"block_number" gorm:"column:block_number;index:k_block_number;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT ''"`
+	UndoTable   string    `json:"undo_table" gorm:"column:undo_table;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'table written by the block'"`
+	UndoType    int       `json:"undo_type" gorm:"column:undo_type;type:smallint(6) NOT NULL DEFAULT '0' COMMENT '1: delete row 2: restore row'"`
+	RowId       uint64    `json:"row_id" gorm:"column:row_id;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT 'primary key of the row'"`
+	RowData     string    `json:"row_data" gorm:"column:row_data;type:mediumtext CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci COMMENT 'row before the block, json'"`
+	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at;type:timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT ''"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"column:updated_at;type:timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT ''"`
+}
+
+const (
+	TableNameBlockUndoInfo = "t_block_undo_info"
+
+	UndoTypeDelete  = 1 // the row was inserted by the block
+	UndoTypeRestore = 2 // the row was updated or deleted by the block
+)
+
+func (t *TableBlockUndoInfo) TableName() string {
+	return TableNameBlockUndoInfo
+}
+
+// undoModelMap is the tables whose changes are journaled per block and reverted on chain reorg,
+// each DbDao has its own, see RegisterUndoModel
+type undoModelMap map[string]func() interface{}
+
+func newUndoModelMap() undoModelMap {
+	m := make(undoModelMap, len(undoModels))
+	for k, v := range undoModels {
+		m[k] = v
+	}
+	return m
+}
+
+// the tables of das_database journaled by every DbDao
+var undoModels = undoModelMap{
+	TableNameAccountInfo:      func() interface{} { return &TableAccountInfo{} },
+	TableNameIncomeCellInfo:   func() interface{} { return &TableIncomeCellInfo{} },
+	TableNameOfferInfo:        func() interface{} { return &TableOfferInfo{} },
+	TableNameRebateInfo:       func() interface{} { return &TableRebateInfo{} },
+	TableNameRecordsInfo:      func() interface{} { return &TableRecordsInfo{} },
+	TableNameReverseInfo:      func() interface{} { return &TableReverseInfo{} },
+	TableNameSmtInfo:          func() interface{} { return &TableSmtInfo{} },
+	TableNameTradeDealInfo:    func() interface{} { return &TableTradeDealInfo{} },
+	TableNameTradeInfo:        func() interface{} { return &TableTradeInfo{} },
+	TableNameTransactionInfo:  func() interface{} { return &TableTransactionInfo{} },
+	TableNameCustomScriptInfo: func() interface{} { return &TableCustomScriptInfo{} },
+	TableNameTradeHistoryInfo: func() interface{} { return &TableTradeHistoryInfo{} },
+	TableNameDeadLetterInfo:   func() interface{} { return &TableDeadLetterInfo{} },
+	TableNameAccountHistory:   func() interface{} { return &TableAccountHistory{} },
+	TableNameRecordsHistory:   func() interface{} { return &TableRecordsHistory{} },
+	TableNameCrossChainInfo:   func() interface{} { return &TableCrossChainInfo{} },
+}
+
+type ctxKeyBlockNumber struct{}
+
+// WithBlockNumber returns a DbDao whose writes are journaled under blockNumber,
+// so that they can be reverted by RollbackBlock.
+func (d *DbDao) WithBlockNumber(blockNumber uint64) *DbDao {
+	ctx := context.WithValue(d.db.Statement.Context, ctxKeyBlockNumber{}, blockNumber)
+	return &DbDao{db: d.db.WithContext(ctx), network: d.network, undoModels: d.undoModels}
+}
+
+// RollbackBlock reverts every journaled change of the blocks >= blockNumber in reverse order
+// and removes those blocks from t_block_info.
+func (d *DbDao) RollbackBlock(blockNumber uint64) error {
+	changeFeed := config.Cfg.Policy(d.network).ChangeFeed.Enable
+	return d.transaction(func(tx *gorm.DB) error {
+		var list []TableBlockUndoInfo
+		if err := tx.Where("block_number >= ?", blockNumber).Order("id DESC").Find(&list).Error; err != nil {
+			return err
+		}
+
+		var changes []TableChangeInfo
+		for _, v := range list {
+			newModel, ok := d.undoModels[v.UndoTable]
+			if !ok {
+				return fmt.Errorf("unknown undo table: %s", v.UndoTable)
+			}
+			// the reverted rows go to the change feed as well
+			collector := &RowCollector{}
+			undoTx := tx
+			if changeFeed {
+				undoTx = tx.WithContext(context.WithValue(tx.Statement.Context, ctxKeyRowCollector{}, collector))
+			}
+			if err := undoTx.Where("id = ?", v.RowId).Delete(newModel()).Error; err != nil {
+				return err
+			}
+			if v.UndoType == UndoTypeRestore {
+				row := newModel()
+				if err := json.Unmarshal([]byte(v.RowData), row); err != nil {
+					return fmt.Errorf("json.Unmarshal err: %s", err.Error())
+				}
+				if err := undoTx.Create(row).Error; err != nil {
+					return err
+				}
+			}
+			rowChanges, err := changeList(tx, v.BlockNumber, "", ChangeActionRollback, collector.Rows())
+			if err != nil {
+				return err
+			}
+			changes = append(changes, rowChanges...)
+		}
+		if len(changes) > 0 {
+			if err := tx.Create(&changes).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("block_number >= ?", blockNumber).Delete(&TableBlockUndoInfo{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("block_number >= ?", blockNumber).Delete(&TableBlockInfo{}).Error; err != nil {
+			return err
+		}
+
+		if err := revertEventOutbox(tx, blockNumber); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (d *DbDao) DeleteBlockUndoInfo(blockNumber uint64) error {
+	return d.db.Where("block_number < ?", blockNumber).Delete(&TableBlockUndoInfo{}).Error
+}
+
+// registerUndoCallbacks journals the rows touched by every create, update and delete
+// executed with a block number in its context, inside the same transaction as the write.
+func registerUndoCallbacks(db *gorm.DB, m undoModelMap) error {
+	if err := db.Callback().Create().Before("gorm:create").Register("das:undo_before_create", m.undoBeforeCreate); err != nil {
+		return err
+	}
+	if err := db.Callback().Create().After("gorm:create").Register("das:undo_after_create", m.undoAfterCreate); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("das:undo_before_update", m.undoBeforeWhere); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("gorm:delete").Register("das:undo_before_delete", m.undoBeforeWhere); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m undoModelMap) undoBlockNumber(db *gorm.DB) (uint64, bool) {
+	if db.Error != nil || db.DryRun || db.Statement.Schema == nil || db.Statement.Context == nil {
+		return 0, false
+	}
+	if _, ok := m[db.Statement.Table]; !ok {
+		return 0, false
+	}
+	blockNumber, ok := db.Statement.Context.Value(ctxKeyBlockNumber{}).(uint64)
+	return blockNumber, ok
+}
+
+// snapshot the rows matched by the update / delete conditions
+func (m undoModelMap) undoBeforeWhere(db *gorm.DB) {
+	blockNumber, ok := m.undoBlockNumber(db)
+	if !ok {
+		return
+	}
+	where, ok := db.Statement.Clauses["WHERE"]
+	if !ok {
+		return
+	}
+	rows := reflect.New(reflect.SliceOf(db.Statement.Schema.ModelType))
+	if err := db.Session(&gorm.Session{NewDB: true}).Table(db.Statement.Table).
+		Clauses(where.Expression).Find(rows.Interface()).Error; err != nil {
+		_ = db.AddError(fmt.Errorf("undo snapshot err: %s", err.Error()))
+		return
+	}
+	if err := createUndoRestore(db, blockNumber, rows.Elem()); err != nil {
+		_ = db.AddError(err)
+	}
+}
+
+const undoInstanceKeyNewRows = "das:undo_new_rows"
+
+// snapshot the rows an upsert will overwrite, remember the ones it will insert
+func (m undoModelMap) undoBeforeCreate(db *gorm.DB) {
+	blockNumber, ok := m.undoBlockNumber(db)
+	if !ok {
+		return
+	}
+	uk := uniqueIndexFields(db.Statement.Schema)
+
+	var newRows []reflect.Value
+	for _, v := range undoReflectValues(db.Statement.ReflectValue) {
+		if len(uk) == 0 {
+			newRows = append(newRows, v)
+			continue
+		}
+		rows := reflect.New(reflect.SliceOf(db.Statement.Schema.ModelType))
+		if err := db.Session(&gorm.Session{NewDB: true}).Table(db.Statement.Table).
+			Where(uniqueIndexConditions(db.Statement.Context, uk, v)).Find(rows.Interface()).Error; err != nil {
+			_ = db.AddError(fmt.Errorf("undo snapshot err: %s", err.Error()))
+			return
+		}
+		if rows.Elem().Len() == 0 {
+			newRows = append(newRows, v)
+		} else if err := createUndoRestore(db, blockNumber, rows.Elem()); err != nil {
+			_ = db.AddError(err)
+			return
+		}
+	}
+	db.InstanceSet(undoInstanceKeyNewRows, newRows)
+}
+
+func (m undoModelMap) undoAfterCreate(db *gorm.DB) {
+	blockNumber, ok := m.undoBlockNumber(db)
+	if !ok {
+		return
+	}
+	value, ok := db.InstanceGet(undoInstanceKeyNewRows)
+	if !ok {
+		return
+	}
+	newRows := value.([]reflect.Value)
+	uk := uniqueIndexFields(db.Statement.Schema)
+	pk := db.Statement.Schema.PrioritizedPrimaryField
+
+	var list []TableBlockUndoInfo
+	for _, v := range newRows {
+		// ids of upserted rows are not reliable, read them back by unique index
+		if len(uk) > 0 {
+			row := reflect.New(db.Statement.Schema.ModelType)
+			if err := db.Session(&gorm.Session{NewDB: true}).Table(db.Statement.Table).
+				Where(uniqueIndexConditions(db.Statement.Context, uk, v)).Limit(1).Find(row.Interface()).Error; err != nil {
+				_ = db.AddError(fmt.Errorf("undo find err: %s", err.Error()))
+				return
+			}
+			v = row.Elem()
+		}
+		id, _ := pk.ValueOf(db.Statement.Context, v)
+		rowId, ok := id.(uint64)
+		if !ok || rowId == 0 {
+			continue
+		}
+		list = append(list, TableBlockUndoInfo{
+			BlockNumber: blockNumber,
+			UndoTable:   db.Statement.Table,
+			UndoType:    UndoTypeDelete,
+			RowId:       rowId,
+		})
+	}
+	if len(list) > 0 {
+		if err := db.Session(&gorm.Session{NewDB: true}).Create(&list).Error; err != nil {
+			_ = db.AddError(fmt.Errorf("undo create err: %s", err.Error()))
+		}
+	}
+}
+
+func createUndoRestore(db *gorm.DB, blockNumber uint64, rows reflect.Value) error {
+	pk := db.Statement.Schema.PrioritizedPrimaryField
+	var list []TableBlockUndoInfo
+	for i := 0; i < rows.Len(); i++ {
+		id, _ := pk.ValueOf(db.Statement.Context, rows.Index(i))
+		rowId, _ := id.(uint64)
+		data, err := json.Marshal(rows.Index(i).Interface())
+		if err != nil {
+			return fmt.Errorf("json.Marshal err: %s", err.Error())
+		}
+		list = append(list, TableBlockUndoInfo{
+			BlockNumber: blockNumber,
+			UndoTable:   db.Statement.Table,
+			UndoType:    UndoTypeRestore,
+			RowId:       rowId,
+			RowData:     string(data),
+		})
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	if err := db.Session(&gorm.Session{NewDB: true}).Create(&list).Error; err != nil {
+		return fmt.Errorf("undo create err: %s", err.Error())
+	}
+	return nil
+}
+
+func undoReflectValues(value reflect.Value) (list []reflect.Value) {
+	value = reflect.Indirect(value)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			list = append(list, reflect.Indirect(value.Index(i)))
+		}
+	case reflect.Struct:
+		list = append(list, value)
+	}
+	return
+}
+
+var mapUniqueIndexFields sync.Map
+
+// uniqueIndexFields returns the fields of the first unique index of the table, all our tables have at most one
+func uniqueIndexFields(s *schema.Schema) []*schema.Field {
+	if v, ok := mapUniqueIndexFields.Load(s.Table); ok {
+		return v.([]*schema.Field)
+	}
+	var names []string
+	indexes := s.ParseIndexes()
+	for name, idx := range indexes {
+		if idx.Class == "UNIQUE" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var fields []*schema.Field
+	if len(names) > 0 {
+		for _, v := range indexes[names[0]].Fields {
+			fields = append(fields, v.Field)
+		}
+	}
+	mapUniqueIndexFields.Store(s.Table, fields)
+	return fields
+}
+
+func uniqueIndexConditions(ctx context.Context, fields []*schema.Field, value reflect.Value) map[string]interface{} {
+	conditions := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		conditions[f.DBName], _ = f.ValueOf(ctx, value)
+	}
+	return conditions
+}
@@ -0,0 +1,94 @@
+package dao
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gorm.io/gorm"
+	"reflect"
+	"time"
+)
+
+type TableChangeInfo struct {
+	Id          uint64    `json:"id" gorm:"column:id;primaryKey;type:bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT 'cursor of the change feed'"`
+	BlockNumber uint64    `json:"block_number" gorm:"column:block_number;index:k_block_number;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT ''"`
+	TxHash      string    `json:"tx_hash" gorm:"column:tx_hash;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'empty for the changes of a rollback'"`
+	Action      string    `json:"action" gorm:"column:action;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	ChangeTable string    `json:"change_table" gorm:"column:change_table;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	RowKey      string    `json:"row_key" gorm:"column:row_key;type:varchar(1024) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'unique index (or primary key) of the row, json'"`
+	Operation   string    `json:"operation" gorm:"column:operation;type:varchar(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'upsert or delete'"`
+	RowData     string    `json:"row_data" gorm:"column:row_data;type:mediumtext CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci COMMENT 'row after an upsert, json'"`
+	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at;type:timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT ''"`
+}
+
+const (
+	TableNameChangeInfo = "t_change_info"
+
+	ChangeActionRollback = "rollback" // the changes reverting a block on chain reorg
+	ChangeActionRepair   = "repair"   // the changes of an account repair, see BlockParser.RepairAccount
+)
+
+func (t *TableChangeInfo) TableName() string {
+	return TableNameChangeInfo
+}
+
+// CreateChangeList appends the rows written by a tx to the change feed
+func (d *DbDao) CreateChangeList(blockNumber uint64, txHash, action string, rows []RowChange) error {
+	list, err := changeList(d.db, blockNumber, txHash, action, rows)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	return d.db.Create(&list).Error
+}
+
+func changeList(db *gorm.DB, blockNumber uint64, txHash, action string, rows []RowChange) ([]TableChangeInfo, error) {
+	list := make([]TableChangeInfo, 0, len(rows))
+	for _, v := range rows {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(v.Row); err != nil {
+			return nil, fmt.Errorf("schema parse err: %s", err.Error())
+		}
+		value := reflect.Indirect(reflect.ValueOf(v.Row))
+		fields := uniqueIndexFields(stmt.Schema)
+		if len(fields) == 0 {
+			fields = stmt.Schema.PrimaryFields
+		}
+		rowKey, err := json.Marshal(uniqueIndexConditions(context.Background(), fields, value))
+		if err != nil {
+			return nil, fmt.Errorf("json.Marshal err: %s", err.Error())
+		}
+		change := TableChangeInfo{
+			BlockNumber: blockNumber,
+			TxHash:      txHash,
+			Action:      action,
+			ChangeTable: v.Table,
+			RowKey:      string(rowKey),
+			Operation:   v.Operation,
+		}
+		// deletes are tombstones, only their key is kept
+		if v.Operation == RowOperationUpsert {
+			rowData, err := json.Marshal(v.Row)
+			if err != nil {
+				return nil, fmt.Errorf("json.Marshal err: %s", err.Error())
+			}
+			change.RowData = string(rowData)
+		}
+		list = append(list, change)
+	}
+	return list, nil
+}
+
+// FindChangeList returns the changes after the cursor, or from the block sinceBlock if no cursor is given
+func (d *DbDao) FindChangeList(cursor, sinceBlock uint64, limit int) (list []TableChangeInfo, err error) {
+	db := d.db
+	if cursor > 0 {
+		db = db.Where("id > ?", cursor)
+	} else {
+		db = db.Where("block_number >= ?", sinceBlock)
+	}
+	err = db.Order("id").Limit(limit).Find(&list).Error
+	return
+}
@@ -0,0 +1,87 @@
+package dao
+
+import (
+	"github.com/dotbitHQ/das-lib/common"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type TableCrossChainInfo struct {
+	Id                   uint64           `json:"id" gorm:"column:id;primaryKey;type:bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT ''"`
+	AccountId            string           `json:"account_id" gorm:"column:account_id;uniqueIndex:uk_ai_lth,priority:1;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'hash of account'"`
+	Account              string           `json:"account" gorm:"column:account;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	CoinType             string           `json:"coin_type" gorm:"column:coin_type;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'slip44 coin type of the target chain'"`
+	ChainId              string           `json:"chain_id" gorm:"column:chain_id;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	OwnerChainType       common.ChainType `json:"owner_chain_type" gorm:"column:owner_chain_type;type:smallint(6) NOT NULL DEFAULT '0' COMMENT ''"`
+	Owner                string           `json:"owner" gorm:"column:owner;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'owner when locked'"`
+	LockBlockNumber      uint64           `json:"lock_block_number" gorm:"column:lock_block_number;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT ''"`
+	LockTimestamp        uint64           `json:"lock_timestamp" gorm:"column:lock_timestamp;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT ''"`
+	LockTxHash           string           `json:"lock_tx_hash" gorm:"column:lock_tx_hash;uniqueIndex:uk_ai_lth,priority:2;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	UnlockBlockNumber    uint64           `json:"unlock_block_number" gorm:"column:unlock_block_number;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT ''"`
+	UnlockTimestamp      uint64           `json:"unlock_timestamp" gorm:"column:unlock_timestamp;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT ''"`
+	UnlockTxHash         string           `json:"unlock_tx_hash" gorm:"column:unlock_tx_hash;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	UnlockOwnerChainType common.ChainType `json:"unlock_owner_chain_type" gorm:"column:unlock_owner_chain_type;type:smallint(6) NOT NULL DEFAULT '0' COMMENT ''"`
+	UnlockOwner          string           `json:"unlock_owner" gorm:"column:unlock_owner;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'owner when unlocked'"`
+	IsTrans              bool             `json:"is_trans" gorm:"column:is_trans;type:tinyint(1) NOT NULL DEFAULT '0' COMMENT 'owner changed on unlock'"`
+	Status               int              `json:"status" gorm:"column:status;index:k_status;type:smallint(6) NOT NULL DEFAULT '0' COMMENT '0: locked 1: unlocked'"`
+	CreatedAt            time.Time        `json:"created_at" gorm:"column:created_at;type:timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT ''"`
+	UpdatedAt            time.Time        `json:"updated_at" gorm:"column:updated_at;type:timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT ''"`
+}
+
+const (
+	TableNameCrossChainInfo = "t_cross_chain_info"
+
+	CrossChainStatusLocked   = 0
+	CrossChainStatusUnlocked = 1
+)
+
+func (t *TableCrossChainInfo) TableName() string {
+	return TableNameCrossChainInfo
+}
+
+func createCrossChainLock(tx *gorm.DB, crossChainInfo TableCrossChainInfo) error {
+	return tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{
+			"account", "coin_type", "chain_id", "owner_chain_type", "owner",
+			"lock_block_number", "lock_timestamp", "status",
+		}),
+	}).Create(&crossChainInfo).Error
+}
+
+// updateCrossChainUnlock closes the lock of the account, locks indexed before t_cross_chain_info existed get a row without lock info
+func updateCrossChainUnlock(tx *gorm.DB, crossChainInfo TableCrossChainInfo) error {
+	res := tx.Select("unlock_block_number", "unlock_timestamp", "unlock_tx_hash",
+		"unlock_owner_chain_type", "unlock_owner", "is_trans", "status").
+		Where("account_id = ? AND status = ?", crossChainInfo.AccountId, CrossChainStatusLocked).
+		Updates(crossChainInfo)
+	if res.Error != nil {
+		return res.Error
+	} else if res.RowsAffected > 0 {
+		return nil
+	}
+	return tx.Create(&crossChainInfo).Error
+}
+
+func (d *DbDao) FindCrossChainLockedList(coinType string, limit, offset int) (list []TableCrossChainInfo, err error) {
+	db := d.db.Where("status = ?", CrossChainStatusLocked)
+	if coinType != "" {
+		db = db.Where("coin_type = ?", coinType)
+	}
+	err = db.Order("lock_block_number DESC, id DESC").Limit(limit).Offset(offset).Find(&list).Error
+	return
+}
+
+func (d *DbDao) GetCrossChainLockedCount(coinType string) (count int64, err error) {
+	db := d.db.Model(TableCrossChainInfo{}).Where("status = ?", CrossChainStatusLocked)
+	if coinType != "" {
+		db = db.Where("coin_type = ?", coinType)
+	}
+	err = db.Count(&count).Error
+	return
+}
+
+func (d *DbDao) FindCrossChainListByAccountId(accountId string) (list []TableCrossChainInfo, err error) {
+	err = d.db.Where("account_id = ?", accountId).Order("id DESC").Find(&list).Error
+	return
+}
@@ -25,7 +25,7 @@ func (t *TableCustomScriptInfo) TableName() string {
 }
 
 func (d *DbDao) UpdateCustomScript(cs TableCustomScriptInfo, accountCellOutpoint string, transactionInfo TableTransactionInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if err := tx.Model(TableAccountInfo{}).Where("account_id=?", cs.AccountId).
 			Updates(map[string]interface{}{
 				"outpoint": accountCellOutpoint,
@@ -0,0 +1,73 @@
+package dao
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type TableDeadLetterInfo struct {
+	Id             uint64    `json:"id" gorm:"column:id;primaryKey;type:bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT ''"`
+	BlockNumber    uint64    `json:"block_number" gorm:"column:block_number;index:k_block_number;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT ''"`
+	BlockTimestamp uint64    `json:"block_timestamp" gorm:"column:block_timestamp;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT ''"`
+	TxHash         string    `json:"tx_hash" gorm:"column:tx_hash;uniqueIndex:uk_tx_hash;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	Action         string    `json:"action" gorm:"column:action;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	ErrMsg         string    `json:"err_msg" gorm:"column:err_msg;type:text CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci COMMENT 'error of the last failure'"`
+	RetryCount     int       `json:"retry_count" gorm:"column:retry_count;type:int(11) NOT NULL DEFAULT '0' COMMENT 'failures before quarantined'"`
+	Status         int       `json:"status" gorm:"column:status;index:k_status;type:smallint(6) NOT NULL DEFAULT '0' COMMENT '0: quarantined 1: released 2: processed'"`
+	CreatedAt      time.Time `json:"created_at" gorm:"column:created_at;type:timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT ''"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"column:updated_at;type:timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT ''"`
+}
+
+const (
+	TableNameDeadLetterInfo = "t_dead_letter_info"
+
+	DeadLetterStatusQuarantined = 0 // skipped by the parser
+	DeadLetterStatusReleased    = 1 // waiting to be re-processed
+	DeadLetterStatusProcessed   = 2
+)
+
+func (t *TableDeadLetterInfo) TableName() string {
+	return TableNameDeadLetterInfo
+}
+
+func (d *DbDao) CreateDeadLetterInfo(deadLetter TableDeadLetterInfo) error {
+	return d.db.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{
+			"block_number", "block_timestamp", "action", "err_msg", "retry_count", "status",
+		}),
+	}).Create(&deadLetter).Error
+}
+
+func (d *DbDao) FindDeadLetterList(status int, limit, offset int) (list []TableDeadLetterInfo, err error) {
+	err = d.db.Where("status = ?", status).Order("id").Limit(limit).Offset(offset).Find(&list).Error
+	return
+}
+
+func (d *DbDao) GetDeadLetterCount(status int) (count int64, err error) {
+	err = d.db.Model(TableDeadLetterInfo{}).Where("status = ?", status).Count(&count).Error
+	return
+}
+
+// ReleaseDeadLetter marks the quarantined txs to be re-processed by the parser
+func (d *DbDao) ReleaseDeadLetter(ids []uint64) (int64, error) {
+	res := d.db.Model(TableDeadLetterInfo{}).
+		Where("id IN ? AND status = ?", ids, DeadLetterStatusQuarantined).
+		Update("status", DeadLetterStatusReleased)
+	return res.RowsAffected, res.Error
+}
+
+func (d *DbDao) UpdateDeadLetterProcessed(id uint64) error {
+	return d.db.Model(TableDeadLetterInfo{}).Where("id = ?", id).
+		Update("status", DeadLetterStatusProcessed).Error
+}
+
+// UpdateDeadLetterFailed quarantines the released tx again after a failed re-processing
+func (d *DbDao) UpdateDeadLetterFailed(id uint64, errMsg string) error {
+	return d.db.Model(TableDeadLetterInfo{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":      DeadLetterStatusQuarantined,
+			"err_msg":     errMsg,
+			"retry_count": gorm.Expr("retry_count + 1"),
+		}).Error
+}
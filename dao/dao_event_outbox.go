@@ -0,0 +1,90 @@
+package dao
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+type TableEventOutbox struct {
+	Id             uint64    `json:"id" gorm:"column:id;primaryKey;type:bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT 'event id'"`
+	EventType      string    `json:"event_type" gorm:"column:event_type;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	BlockNumber    uint64    `json:"block_number" gorm:"column:block_number;index:k_block_number;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT ''"`
+	BlockTimestamp uint64    `json:"block_timestamp" gorm:"column:block_timestamp;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT ''"`
+	TxHash         string    `json:"tx_hash" gorm:"column:tx_hash;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	Action         string    `json:"action" gorm:"column:action;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	AccountId      string    `json:"account_id" gorm:"column:account_id;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'hash of account'"`
+	Account        string    `json:"account" gorm:"column:account;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	RevertOf       uint64    `json:"revert_of" gorm:"column:revert_of;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT 'id of the event reverted by an EventReverted'"`
+	Payload        string    `json:"payload" gorm:"column:payload;type:mediumtext CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci COMMENT 'rows written by the tx, json'"`
+	Status         int       `json:"status" gorm:"column:status;index:k_status;type:smallint(6) NOT NULL DEFAULT '0' COMMENT '0: pending 1: published 2: reverted'"`
+	CreatedAt      time.Time `json:"created_at" gorm:"column:created_at;type:timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT ''"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"column:updated_at;type:timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT ''"`
+}
+
+const (
+	TableNameEventOutbox = "t_event_outbox"
+
+	EventStatusPending   = 0
+	EventStatusPublished = 1
+	EventStatusReverted  = 2 // its block was rolled back, an EventReverted is emitted instead
+
+	EventTypeReverted = "EventReverted"
+)
+
+func (t *TableEventOutbox) TableName() string {
+	return TableNameEventOutbox
+}
+
+func (d *DbDao) CreateEventOutbox(list []TableEventOutbox) error {
+	if len(list) == 0 {
+		return nil
+	}
+	return d.db.Create(&list).Error
+}
+
+// FindPendingEventList returns the events to publish in the order they were written
+func (d *DbDao) FindPendingEventList(limit int) (list []TableEventOutbox, err error) {
+	err = d.db.Where("status = ?", EventStatusPending).Order("id").Limit(limit).Find(&list).Error
+	return
+}
+
+func (d *DbDao) UpdateEventPublished(ids []uint64) error {
+	return d.db.Model(TableEventOutbox{}).Where("id IN ? AND status = ?", ids, EventStatusPending).
+		Update("status", EventStatusPublished).Error
+}
+
+// revertEventOutbox emits an EventReverted for every event of the blocks >= blockNumber.
+// Events not yet published are reverted as well since the relay may be publishing them,
+// consumers ignore the reverts of unknown events.
+func revertEventOutbox(tx *gorm.DB, blockNumber uint64) error {
+	var list []TableEventOutbox
+	if err := tx.Where("block_number >= ? AND status IN ? AND event_type != ?",
+		blockNumber, []int{EventStatusPending, EventStatusPublished}, EventTypeReverted).
+		Order("id").Find(&list).Error; err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return nil
+	}
+
+	var ids []uint64
+	var reverts []TableEventOutbox
+	for _, v := range list {
+		ids = append(ids, v.Id)
+		reverts = append(reverts, TableEventOutbox{
+			EventType:      EventTypeReverted,
+			BlockNumber:    v.BlockNumber,
+			BlockTimestamp: v.BlockTimestamp,
+			TxHash:         v.TxHash,
+			Action:         v.Action,
+			AccountId:      v.AccountId,
+			Account:        v.Account,
+			RevertOf:       v.Id,
+			Payload:        "{}",
+		})
+	}
+	if err := tx.Model(TableEventOutbox{}).Where("id IN ?", ids).Update("status", EventStatusReverted).Error; err != nil {
+		return err
+	}
+	return tx.Create(&reverts).Error
+}
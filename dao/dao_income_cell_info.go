@@ -74,7 +74,7 @@ func (d *DbDao) FindIncomeCellInfoListByOutpoint(outpoint string) (incomeCellInf
 }
 
 func (d *DbDao) ConsolidateIncome(outpoints []string, incomeCellInfos []TableIncomeCellInfo, transactionInfos []TableTransactionInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if err := tx.Where("outpoint IN ?", outpoints).Delete(&TableIncomeCellInfo{}).Error; err != nil {
 			return err
 		}
@@ -105,7 +105,7 @@ func (d *DbDao) ConsolidateIncome(outpoints []string, incomeCellInfos []TableInc
 }
 
 func (d *DbDao) RenewAccount(outpoints []string, incomeCellInfos []TableIncomeCellInfo, accountInfo TableAccountInfo, transactionInfo TableTransactionInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if err := tx.Where("outpoint IN ?", outpoints).Delete(&TableIncomeCellInfo{}).Error; err != nil {
 			return err
 		}
@@ -0,0 +1,116 @@
+package dao
+
+import (
+	"das_database/metrics"
+	"gorm.io/gorm"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const metricsStartKey = "das:metrics_start"
+
+// registerMetricsCallbacks observes the latency of every statement, labelled by the network and the DbDao method issuing it.
+// The latency of the db transactions is observed by transaction and Transaction.
+func registerMetricsCallbacks(db *gorm.DB, network string) error {
+	metricsAfter := func(db *gorm.DB) {
+		start, ok := db.InstanceGet(metricsStartKey)
+		if !ok {
+			return
+		}
+		metrics.ObserveDb(network, callerDbDaoMethod(), start.(time.Time), db.Error)
+	}
+	cb := db.Callback()
+	if err := cb.Create().Before("*").Register("das:metrics_before_create", metricsBefore); err != nil {
+		return err
+	}
+	if err := cb.Create().After("*").Register("das:metrics_after_create", metricsAfter); err != nil {
+		return err
+	}
+	if err := cb.Query().Before("*").Register("das:metrics_before_query", metricsBefore); err != nil {
+		return err
+	}
+	if err := cb.Query().After("*").Register("das:metrics_after_query", metricsAfter); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("*").Register("das:metrics_before_update", metricsBefore); err != nil {
+		return err
+	}
+	if err := cb.Update().After("*").Register("das:metrics_after_update", metricsAfter); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("*").Register("das:metrics_before_delete", metricsBefore); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("*").Register("das:metrics_after_delete", metricsAfter); err != nil {
+		return err
+	}
+	if err := cb.Raw().Before("*").Register("das:metrics_before_raw", metricsBefore); err != nil {
+		return err
+	}
+	if err := cb.Raw().After("*").Register("das:metrics_after_raw", metricsAfter); err != nil {
+		return err
+	}
+	return nil
+}
+
+func metricsBefore(db *gorm.DB) {
+	db.InstanceSet(metricsStartKey, time.Now())
+}
+
+// transaction runs fn in a db transaction, its latency is labelled by the DbDao method calling it
+func (d *DbDao) transaction(fn func(tx *gorm.DB) error) error {
+	return d.timedTransaction(callerFuncName(2), fn)
+}
+
+func (d *DbDao) timedTransaction(method string, fn func(tx *gorm.DB) error) error {
+	start := time.Now()
+	err := d.db.Transaction(fn)
+	metrics.ObserveDbTransaction(d.network, method, start, err)
+	return err
+}
+
+// callerFuncName returns the function skip frames up the stack, e.g. BuyAccount for the DbDao methods,
+// BlockParser.parsingBlockData for the others, their closures are reported as the function itself
+func callerFuncName(skip int) string {
+	pc, _, _, ok := runtime.Caller(skip)
+	if !ok {
+		return "unknown"
+	}
+	name := runtime.FuncForPC(pc).Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[i+1:] // package
+	}
+	name = strings.NewReplacer("(*", "", ")", "").Replace(name)
+	name = strings.TrimPrefix(name, "DbDao.")
+	parts := strings.Split(name, ".")
+	for len(parts) > 1 && strings.HasPrefix(parts[len(parts)-1], "func") {
+		parts = parts[:len(parts)-1]
+	}
+	return strings.Join(parts, ".")
+}
+
+const dbDaoMethodPrefix = "das_database/dao.(*DbDao)."
+
+// callerDbDaoMethod returns the innermost DbDao method on the call stack
+func callerDbDaoMethod() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if i := strings.Index(frame.Function, dbDaoMethodPrefix); i >= 0 {
+			method := frame.Function[i+len(dbDaoMethodPrefix):]
+			if j := strings.Index(method, "."); j > 0 {
+				method = method[:j] // closures, e.g. BuyAccount.func1
+			}
+			return method
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
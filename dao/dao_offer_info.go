@@ -36,7 +36,7 @@ func (t *TableOfferInfo) TableName() string {
 }
 
 func (d *DbDao) MakeOffer(offerInfo TableOfferInfo, transactionInfo TableTransactionInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if err := tx.Clauses(clause.OnConflict{
 			DoUpdates: clause.AssignmentColumns([]string{
 				"account_id", "account", "algorithm_id", "chain_type", "address",
@@ -60,7 +60,7 @@ func (d *DbDao) MakeOffer(offerInfo TableOfferInfo, transactionInfo TableTransac
 }
 
 func (d *DbDao) EditOffer(oldOutpoint string, offerInfo TableOfferInfo, transactionInfo TableTransactionInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if err := tx.Select(
 			"block_number", "outpoint", "account_id", "account",
 			"block_timestamp", "price", "message").
@@ -82,7 +82,7 @@ func (d *DbDao) EditOffer(oldOutpoint string, offerInfo TableOfferInfo, transact
 }
 
 func (d *DbDao) CancelOffer(oldOutpoints []string, transactionInfo TableTransactionInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if err := tx.Where("outpoint IN ?", oldOutpoints).Delete(&TableOfferInfo{}).Error; err != nil {
 			return err
 		}
@@ -101,7 +101,7 @@ func (d *DbDao) CancelOffer(oldOutpoints []string, transactionInfo TableTransact
 }
 
 func (d *DbDao) AcceptOffer(incomeCellInfos []TableIncomeCellInfo, accountInfo TableAccountInfo, offerOutpoint string, tradeDealInfo TableTradeDealInfo, transactionInfoBuy, transactionInfoSale TableTransactionInfo, rebateInfos []TableRebateInfo, recordsInfos []TableRecordsInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if len(incomeCellInfos) > 0 {
 			if err := tx.Clauses(clause.OnConflict{
 				DoUpdates: clause.AssignmentColumns([]string{
@@ -173,3 +173,25 @@ func (d *DbDao) AcceptOffer(incomeCellInfos []TableIncomeCellInfo, accountInfo T
 		return nil
 	})
 }
+
+func (d *DbDao) FindOfferListByAccountId(accountId string, limit, offset int) (list []TableOfferInfo, err error) {
+	err = d.db.Where("account_id = ?", accountId).
+		Order("price DESC, id").Limit(limit).Offset(offset).Find(&list).Error
+	return
+}
+
+func (d *DbDao) GetOfferCountByAccountId(accountId string) (count int64, err error) {
+	err = d.db.Model(TableOfferInfo{}).Where("account_id = ?", accountId).Count(&count).Error
+	return
+}
+
+func (d *DbDao) FindOfferListByAddress(chainType common.ChainType, address string, limit, offset int) (list []TableOfferInfo, err error) {
+	err = d.db.Where("chain_type = ? AND address = ?", chainType, address).
+		Order("block_number DESC, id DESC").Limit(limit).Offset(offset).Find(&list).Error
+	return
+}
+
+func (d *DbDao) GetOfferCountByAddress(chainType common.ChainType, address string) (count int64, err error) {
+	err = d.db.Model(TableOfferInfo{}).Where("chain_type = ? AND address = ?", chainType, address).Count(&count).Error
+	return
+}
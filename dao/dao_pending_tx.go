@@ -0,0 +1,98 @@
+package dao
+
+import (
+	"github.com/dotbitHQ/das-lib/common"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// TablePendingTx is a das tx seen in the tx pool of the node, until it is indexed or dropped
+type TablePendingTx struct {
+	Id          uint64           `json:"id" gorm:"column:id;primaryKey;type:bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT ''"`
+	TxHash      string           `json:"tx_hash" gorm:"column:tx_hash;uniqueIndex:uk_tx_hash;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	Action      string           `json:"action" gorm:"column:action;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	AccountId   string           `json:"account_id" gorm:"column:account_id;index:k_account_id;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'hash of account'"`
+	Account     string           `json:"account" gorm:"column:account;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	ChainType   common.ChainType `json:"chain_type" gorm:"column:chain_type;index:k_ct_a,priority:1;type:smallint(6) NOT NULL DEFAULT '0' COMMENT 'owner of the first das-lock input'"`
+	Address     string           `json:"address" gorm:"column:address;index:k_ct_a,priority:2;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	ToChainType common.ChainType `json:"to_chain_type" gorm:"column:to_chain_type;index:k_tct_ta,priority:1;type:smallint(6) NOT NULL DEFAULT '0' COMMENT 'owner of the first das-lock output'"`
+	ToAddress   string           `json:"to_address" gorm:"column:to_address;index:k_tct_ta,priority:2;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	Status      int              `json:"status" gorm:"column:status;index:k_status;type:smallint(6) NOT NULL DEFAULT '0' COMMENT '0: pending 1: committed 2: rejected'"`
+	BlockNumber uint64           `json:"block_number" gorm:"column:block_number;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT 'block of the committed tx'"`
+	CreatedAt   time.Time        `json:"created_at" gorm:"column:created_at;type:timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT ''"`
+	UpdatedAt   time.Time        `json:"updated_at" gorm:"column:updated_at;type:timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT ''"`
+}
+
+const (
+	TableNamePendingTx = "t_pending_tx"
+
+	PendingTxStatusPending   = 0
+	PendingTxStatusCommitted = 1
+	PendingTxStatusRejected  = 2
+)
+
+func (t *TablePendingTx) TableName() string {
+	return TableNamePendingTx
+}
+
+// CreatePendingTx ignores the txs already tracked
+func (d *DbDao) CreatePendingTx(list []TablePendingTx) error {
+	if len(list) == 0 {
+		return nil
+	}
+	return d.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&list).Error
+}
+
+// FindPendingTxHashes returns the hashes among txHashes already tracked, whatever their status
+func (d *DbDao) FindPendingTxHashes(txHashes []string) (list []string, err error) {
+	if len(txHashes) == 0 {
+		return
+	}
+	err = d.db.Model(&TablePendingTx{}).Where("tx_hash IN ?", txHashes).Pluck("tx_hash", &list).Error
+	return
+}
+
+func (d *DbDao) FindPendingTxByStatus(status int) (list []TablePendingTx, err error) {
+	err = d.db.Where("status = ?", status).Order("id").Find(&list).Error
+	return
+}
+
+func (d *DbDao) UpdatePendingTxCommitted(txHashes []string, blockNumber uint64) error {
+	if len(txHashes) == 0 {
+		return nil
+	}
+	return d.db.Model(&TablePendingTx{}).Where("tx_hash IN ? AND status = ?", txHashes, PendingTxStatusPending).
+		Updates(map[string]interface{}{"status": PendingTxStatusCommitted, "block_number": blockNumber}).Error
+}
+
+func (d *DbDao) UpdatePendingTxRejected(txHash string) error {
+	return d.db.Model(&TablePendingTx{}).Where("tx_hash = ? AND status = ?", txHash, PendingTxStatusPending).
+		Update("status", PendingTxStatusRejected).Error
+}
+
+// DeletePendingTxBefore deletes the committed and rejected txs updated before t
+func (d *DbDao) DeletePendingTxBefore(t time.Time) error {
+	return d.db.Where("status != ? AND updated_at < ?", PendingTxStatusPending, t).Delete(&TablePendingTx{}).Error
+}
+
+type PendingTxFilter struct {
+	AccountId string
+	ChainType common.ChainType
+	Address   string
+	Status    []int
+}
+
+func (d *DbDao) FindPendingTxList(filter PendingTxFilter, limit, offset int) (list []TablePendingTx, err error) {
+	db := d.db
+	if filter.AccountId != "" {
+		db = db.Where("account_id = ?", filter.AccountId)
+	} else {
+		db = db.Where("((chain_type = ? AND address = ?) OR (to_chain_type = ? AND to_address = ?))",
+			filter.ChainType, filter.Address, filter.ChainType, filter.Address)
+	}
+	if len(filter.Status) > 0 {
+		db = db.Where("status IN ?", filter.Status)
+	}
+	err = db.Order("id DESC").Limit(limit).Offset(offset).Find(&list).Error
+	return
+}
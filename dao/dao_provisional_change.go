@@ -0,0 +1,108 @@
+package dao
+
+import (
+	"encoding/json"
+	"fmt"
+	"gorm.io/gorm"
+	"reflect"
+	"time"
+)
+
+// TableProvisionalChange is a row written by a tx of the blocks not yet confirmed, the parser rebuilds them on every new tip
+// and drops them once the blocks are confirmed and indexed, or replaced by a reorg
+type TableProvisionalChange struct {
+	Id          uint64    `json:"id" gorm:"column:id;primaryKey;type:bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT ''"`
+	BlockNumber uint64    `json:"block_number" gorm:"column:block_number;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT ''"`
+	BlockHash   string    `json:"block_hash" gorm:"column:block_hash;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	TxHash      string    `json:"tx_hash" gorm:"column:tx_hash;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	Action      string    `json:"action" gorm:"column:action;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	ChangeTable string    `json:"change_table" gorm:"column:change_table;index:k_ct_ai,priority:1;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	AccountId   string    `json:"account_id" gorm:"column:account_id;index:k_ct_ai,priority:2;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'account_id of the row if any'"`
+	Operation   string    `json:"operation" gorm:"column:operation;type:varchar(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'upsert or delete'"`
+	RowData     string    `json:"row_data" gorm:"column:row_data;type:mediumtext CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci COMMENT 'row after an upsert, before a delete, json'"`
+	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at;type:timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT ''"`
+}
+
+const (
+	TableNameProvisionalChange = "t_provisional_change"
+)
+
+func (t *TableProvisionalChange) TableName() string {
+	return TableNameProvisionalChange
+}
+
+// ProvisionalChangeList converts the rows written by a tx of an unconfirmed block
+func ProvisionalChangeList(blockNumber uint64, blockHash, txHash, action string, rows []RowChange) ([]TableProvisionalChange, error) {
+	list := make([]TableProvisionalChange, 0, len(rows))
+	for _, v := range rows {
+		rowData, err := json.Marshal(v.Row)
+		if err != nil {
+			return nil, fmt.Errorf("json.Marshal err: %s", err.Error())
+		}
+		change := TableProvisionalChange{
+			BlockNumber: blockNumber,
+			BlockHash:   blockHash,
+			TxHash:      txHash,
+			Action:      action,
+			ChangeTable: v.Table,
+			Operation:   v.Operation,
+			RowData:     string(rowData),
+		}
+		if field := reflect.Indirect(reflect.ValueOf(v.Row)).FieldByName("AccountId"); field.IsValid() && field.Kind() == reflect.String {
+			change.AccountId = field.String()
+		}
+		list = append(list, change)
+	}
+	return list, nil
+}
+
+// ReplaceProvisionalChanges swaps all the provisional changes for list, in the order of list
+func (d *DbDao) ReplaceProvisionalChanges(list []TableProvisionalChange) error {
+	return d.transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&TableProvisionalChange{}).Error; err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&list, 500).Error
+	})
+}
+
+// ApplyProvisionalChanges applies the provisional changes of the table for the account to list,
+// a pointer to a slice of the model of the table (e.g. *[]TableRecordsInfo), whose rows are matched by Id
+func (d *DbDao) ApplyProvisionalChanges(table, accountId string, list interface{}) error {
+	var changes []TableProvisionalChange
+	if err := d.db.Where("change_table = ? AND account_id = ?", table, accountId).Order("id").Find(&changes).Error; err != nil {
+		return err
+	}
+	return applyProvisionalChanges(changes, list)
+}
+
+func applyProvisionalChanges(changes []TableProvisionalChange, list interface{}) error {
+	slice := reflect.ValueOf(list).Elem()
+	for _, v := range changes {
+		row := reflect.New(slice.Type().Elem())
+		if err := json.Unmarshal([]byte(v.RowData), row.Interface()); err != nil {
+			return fmt.Errorf("json.Unmarshal err: %s", err.Error())
+		}
+		id := row.Elem().FieldByName("Id").Uint()
+		index := -1
+		for i := 0; i < slice.Len(); i++ {
+			if slice.Index(i).FieldByName("Id").Uint() == id {
+				index = i
+				break
+			}
+		}
+		switch {
+		case v.Operation == RowOperationDelete && index >= 0:
+			slice = reflect.AppendSlice(slice.Slice(0, index), slice.Slice(index+1, slice.Len()))
+		case v.Operation == RowOperationUpsert && index >= 0:
+			slice.Index(index).Set(row.Elem())
+		case v.Operation == RowOperationUpsert:
+			slice = reflect.Append(slice, row.Elem())
+		}
+	}
+	reflect.ValueOf(list).Elem().Set(slice)
+	return nil
+}
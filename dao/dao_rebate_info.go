@@ -36,3 +36,32 @@ const (
 func (t *TableRebateInfo) TableName() string {
 	return TableNameRebateInfo
 }
+
+type RebateSum struct {
+	Total  int64  `json:"total" gorm:"column:total"`
+	Reward uint64 `json:"reward" gorm:"column:reward"`
+}
+
+func (d *DbDao) FindRebateListByInviterId(inviterId string, limit, offset int) (list []TableRebateInfo, err error) {
+	err = d.db.Where("inviter_id = ?", inviterId).
+		Order("block_number DESC, id DESC").Limit(limit).Offset(offset).Find(&list).Error
+	return
+}
+
+func (d *DbDao) GetRebateSumByInviterId(inviterId string) (sum RebateSum, err error) {
+	err = d.db.Model(TableRebateInfo{}).Select("COUNT(*) AS total, IFNULL(SUM(reward),0) AS reward").
+		Where("inviter_id = ?", inviterId).Find(&sum).Error
+	return
+}
+
+func (d *DbDao) FindRebateListByInviterAddress(chainType common.ChainType, address string, limit, offset int) (list []TableRebateInfo, err error) {
+	err = d.db.Where("inviter_chain_type = ? AND inviter_address = ?", chainType, address).
+		Order("block_number DESC, id DESC").Limit(limit).Offset(offset).Find(&list).Error
+	return
+}
+
+func (d *DbDao) GetRebateSumByInviterAddress(chainType common.ChainType, address string) (sum RebateSum, err error) {
+	err = d.db.Model(TableRebateInfo{}).Select("COUNT(*) AS total, IFNULL(SUM(reward),0) AS reward").
+		Where("inviter_chain_type = ? AND inviter_address = ?", chainType, address).Find(&sum).Error
+	return
+}
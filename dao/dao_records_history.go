@@ -0,0 +1,218 @@
+package dao
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"time"
+)
+
+type TableRecordsHistory struct {
+	Id             uint64    `json:"id" gorm:"column:id;primaryKey;type:bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT ''"`
+	BlockNumber    uint64    `json:"block_number" gorm:"column:block_number;index:k_ai_bn,priority:2;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT ''"`
+	BlockTimestamp uint64    `json:"block_timestamp" gorm:"column:block_timestamp;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT ''"`
+	TxHash         string    `json:"tx_hash" gorm:"column:tx_hash;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	Action         string    `json:"action" gorm:"column:action;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	AccountId      string    `json:"account_id" gorm:"column:account_id;index:k_ai_bn,priority:1;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'hash of account'"`
+	Account        string    `json:"account" gorm:"column:account;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	Operation      string    `json:"operation" gorm:"column:operation;type:varchar(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'add, change or remove'"`
+	Key            string    `json:"key" gorm:"column:key;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT ''"`
+	Type           string    `json:"type" gorm:"column:type;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT ''"`
+	Label          string    `json:"label" gorm:"column:label;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT ''"`
+	Value          string    `json:"value" gorm:"column:value;type:varchar(1024) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'value set by the tx'"`
+	Ttl            string    `json:"ttl" gorm:"column:ttl;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT ''"`
+	OldValue       string    `json:"old_value" gorm:"column:old_value;type:varchar(1024) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'value before the tx'"`
+	OldTtl         string    `json:"old_ttl" gorm:"column:old_ttl;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT ''"`
+	CreatedAt      time.Time `json:"created_at" gorm:"column:created_at;type:timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT ''"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"column:updated_at;type:timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT ''"`
+}
+
+const (
+	TableNameRecordsHistory = "t_records_history"
+
+	RecordsOperationAdd    = "add"
+	RecordsOperationChange = "change"
+	RecordsOperationRemove = "remove"
+)
+
+func (t *TableRecordsHistory) TableName() string {
+	return TableNameRecordsHistory
+}
+
+// RecordsEdit is a tx which changed the records of an account
+type RecordsEdit struct {
+	BlockNumber    uint64 `json:"block_number"`
+	BlockTimestamp uint64 `json:"block_timestamp"`
+	TxHash         string `json:"tx_hash"`
+	Action         string `json:"action"`
+}
+
+func (d *DbDao) recordsEditQuery(accountId, key string) *gorm.DB {
+	db := d.db.Model(TableRecordsHistory{}).Where("account_id = ?", accountId)
+	if key != "" {
+		db = db.Where("`key` = ?", key)
+	}
+	return db
+}
+
+func (d *DbDao) FindRecordsEditList(accountId, key string, limit, offset int) (list []RecordsEdit, err error) {
+	err = d.recordsEditQuery(accountId, key).
+		Select("block_number, block_timestamp, tx_hash, action").
+		Group("block_number, block_timestamp, tx_hash, action").
+		Order("block_number DESC, tx_hash").Limit(limit).Offset(offset).Find(&list).Error
+	return
+}
+
+func (d *DbDao) GetRecordsEditCount(accountId, key string) (count int64, err error) {
+	edits := d.recordsEditQuery(accountId, key).Select("block_number, tx_hash").Group("block_number, tx_hash")
+	err = d.db.Table("(?) AS t", edits).Count(&count).Error
+	return
+}
+
+func (d *DbDao) FindRecordsHistoryByBlockNumbers(accountId, key string, blockNumbers []uint64) (list []TableRecordsHistory, err error) {
+	err = d.recordsEditQuery(accountId, key).Where("block_number IN ?", blockNumbers).Order("id").Find(&list).Error
+	return
+}
+
+// registerRecordsHistoryCallbacks diffs the delete-and-reinsert of t_records_info done by a block into t_records_history:
+// every deleted record is recorded as removed, re-inserting it in the same tx turns it into changed, or drops it if unchanged.
+func registerRecordsHistoryCallbacks(db *gorm.DB) error {
+	if err := db.Callback().Delete().Before("gorm:delete").Register("das:records_history_before_delete", recordsHistoryBeforeDelete); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().After("gorm:delete").Register("das:records_history_after_delete", recordsHistoryAfterDelete); err != nil {
+		return err
+	}
+	if err := db.Callback().Create().After("gorm:create").Register("das:records_history_after_create", recordsHistoryAfterCreate); err != nil {
+		return err
+	}
+	return nil
+}
+
+const recordsHistoryInstanceKeyRows = "das:records_history_rows"
+
+func recordsHistoryBlockNumber(db *gorm.DB) (uint64, bool) {
+	if db.Error != nil || db.DryRun || db.Statement.Context == nil || db.Statement.Table != TableNameRecordsInfo {
+		return 0, false
+	}
+	blockNumber, ok := db.Statement.Context.Value(ctxKeyBlockNumber{}).(uint64)
+	return blockNumber, ok
+}
+
+func recordsHistoryBeforeDelete(db *gorm.DB) {
+	if _, ok := recordsHistoryBlockNumber(db); !ok {
+		return
+	}
+	where, ok := db.Statement.Clauses["WHERE"]
+	if !ok {
+		return
+	}
+	var rows []TableRecordsInfo
+	if err := db.Session(&gorm.Session{NewDB: true}).Clauses(where.Expression).Find(&rows).Error; err != nil {
+		_ = db.AddError(fmt.Errorf("records history find err: %s", err.Error()))
+		return
+	}
+	db.InstanceSet(recordsHistoryInstanceKeyRows, rows)
+}
+
+func recordsHistoryAfterDelete(db *gorm.DB) {
+	blockNumber, ok := recordsHistoryBlockNumber(db)
+	if !ok {
+		return
+	}
+	value, ok := db.InstanceGet(recordsHistoryInstanceKeyRows)
+	if !ok {
+		return
+	}
+	info, _ := db.Statement.Context.Value(ctxKeyTxInfo{}).(txInfo)
+	var list []TableRecordsHistory
+	for _, v := range value.([]TableRecordsInfo) {
+		list = append(list, TableRecordsHistory{
+			BlockNumber:    blockNumber,
+			BlockTimestamp: info.blockTimestamp,
+			TxHash:         info.txHash,
+			Action:         info.action,
+			AccountId:      v.AccountId,
+			Account:        v.Account,
+			Operation:      RecordsOperationRemove,
+			Key:            v.Key,
+			Type:           v.Type,
+			Label:          v.Label,
+			OldValue:       v.Value,
+			OldTtl:         v.Ttl,
+		})
+	}
+	if len(list) == 0 {
+		return
+	}
+	if err := db.Session(&gorm.Session{NewDB: true}).Create(&list).Error; err != nil {
+		_ = db.AddError(fmt.Errorf("records history create err: %s", err.Error()))
+	}
+}
+
+func recordsHistoryAfterCreate(db *gorm.DB) {
+	blockNumber, ok := recordsHistoryBlockNumber(db)
+	if !ok {
+		return
+	}
+	info, _ := db.Statement.Context.Value(ctxKeyTxInfo{}).(txInfo)
+	session := db.Session(&gorm.Session{NewDB: true})
+
+	var list []TableRecordsHistory
+	for _, v := range undoReflectValues(db.Statement.ReflectValue) {
+		record, ok := v.Addr().Interface().(*TableRecordsInfo)
+		if !ok {
+			continue
+		}
+		// the version removed by the same tx, if any
+		var removed TableRecordsHistory
+		if err := session.Where(map[string]interface{}{
+			"block_number": blockNumber,
+			"tx_hash":      info.txHash,
+			"account_id":   record.AccountId,
+			"operation":    RecordsOperationRemove,
+			"key":          record.Key,
+			"type":         record.Type,
+			"label":        record.Label,
+		}).Order("id").Limit(1).Find(&removed).Error; err != nil {
+			_ = db.AddError(fmt.Errorf("records history find err: %s", err.Error()))
+			return
+		}
+
+		var err error
+		switch {
+		case removed.Id == 0:
+			list = append(list, TableRecordsHistory{
+				BlockNumber:    blockNumber,
+				BlockTimestamp: info.blockTimestamp,
+				TxHash:         info.txHash,
+				Action:         info.action,
+				AccountId:      record.AccountId,
+				Account:        record.Account,
+				Operation:      RecordsOperationAdd,
+				Key:            record.Key,
+				Type:           record.Type,
+				Label:          record.Label,
+				Value:          record.Value,
+				Ttl:            record.Ttl,
+			})
+		case removed.OldValue == record.Value && removed.OldTtl == record.Ttl:
+			err = session.Where("id = ?", removed.Id).Delete(&TableRecordsHistory{}).Error
+		default:
+			err = session.Model(&TableRecordsHistory{}).Where("id = ?", removed.Id).Updates(map[string]interface{}{
+				"operation": RecordsOperationChange,
+				"value":     record.Value,
+				"ttl":       record.Ttl,
+			}).Error
+		}
+		if err != nil {
+			_ = db.AddError(fmt.Errorf("records history update err: %s", err.Error()))
+			return
+		}
+	}
+	if len(list) == 0 {
+		return
+	}
+	if err := session.Create(&list).Error; err != nil {
+		_ = db.AddError(fmt.Errorf("records history create err: %s", err.Error()))
+	}
+}
@@ -29,7 +29,7 @@ func (t *TableRecordsInfo) TableName() string {
 }
 
 func (d *DbDao) CreateRecordsInfos(accountInfo TableAccountInfo, recordsInfos []TableRecordsInfo, transactionInfo TableTransactionInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if err := tx.Select("block_number", "outpoint").
 			Where("account_id = ?", accountInfo.AccountId).
 			Updates(accountInfo).Error; err != nil {
@@ -58,3 +58,8 @@ func (d *DbDao) CreateRecordsInfos(accountInfo TableAccountInfo, recordsInfos []
 		return nil
 	})
 }
+
+func (d *DbDao) FindRecordsByAccountId(accountId string) (list []TableRecordsInfo, err error) {
+	err = d.db.Where("account_id = ?", accountId).Order("id").Find(&list).Error
+	return
+}
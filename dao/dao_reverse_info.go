@@ -31,7 +31,7 @@ func (t *TableReverseInfo) TableName() string {
 }
 
 func (d *DbDao) DeclareReverseRecord(reverseInfo TableReverseInfo, txInfo TableTransactionInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 
 		if err := tx.Clauses(clause.OnConflict{
 			DoUpdates: clause.AssignmentColumns([]string{
@@ -55,7 +55,7 @@ func (d *DbDao) DeclareReverseRecord(reverseInfo TableReverseInfo, txInfo TableT
 }
 
 func (d *DbDao) RedeclareReverseRecord(lastOutpoint string, reverseInfo TableReverseInfo, txInfo TableTransactionInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 
 		if err := tx.Where(" outpoint=? ", lastOutpoint).Delete(TableReverseInfo{}).Error; err != nil {
 			return err
@@ -83,7 +83,7 @@ func (d *DbDao) RedeclareReverseRecord(lastOutpoint string, reverseInfo TableRev
 }
 
 func (d *DbDao) RetractReverseRecord(listOutpoint []string, txInfo TableTransactionInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 
 		if err := tx.Where(" outpoint IN(?) ", listOutpoint).Delete(TableReverseInfo{}).Error; err != nil {
 			return err
@@ -101,3 +101,9 @@ func (d *DbDao) RetractReverseRecord(listOutpoint []string, txInfo TableTransact
 		return nil
 	})
 }
+
+func (d *DbDao) FindLatestReverseRecord(chainType common.ChainType, address string) (reverseInfo TableReverseInfo, err error) {
+	err = d.db.Where("chain_type = ? AND address = ?", chainType, address).
+		Order("block_number DESC, id DESC").Limit(1).Find(&reverseInfo).Error
+	return
+}
@@ -0,0 +1,180 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"reflect"
+	"sync"
+)
+
+const (
+	RowOperationUpsert = "upsert"
+	RowOperationDelete = "delete"
+)
+
+// RowChange is a row written by a statement, Row is the model struct (e.g. TableAccountInfo) as stored after
+// an upsert, or as it was before a delete
+type RowChange struct {
+	Table     string      `json:"table"`
+	Operation string      `json:"operation"`
+	Row       interface{} `json:"row"`
+}
+
+// RowCollector gathers the rows written through a DbDao returned by WithRowCollector
+type RowCollector struct {
+	lock sync.Mutex
+	rows []RowChange
+}
+
+func (c *RowCollector) add(rows ...RowChange) {
+	c.lock.Lock()
+	c.rows = append(c.rows, rows...)
+	c.lock.Unlock()
+}
+
+// Rows returns the rows collected so far in the order they were written
+func (c *RowCollector) Rows() []RowChange {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]RowChange(nil), c.rows...)
+}
+
+type ctxKeyRowCollector struct{}
+
+// WithRowCollector returns a DbDao whose writes are appended to c
+func (d *DbDao) WithRowCollector(c *RowCollector) *DbDao {
+	ctx := context.WithValue(d.db.Statement.Context, ctxKeyRowCollector{}, c)
+	return &DbDao{db: d.db.WithContext(ctx), network: d.network, undoModels: d.undoModels}
+}
+
+// DB returns the gorm handle of the DbDao, bound to its transaction and context,
+// for the handlers of other services writing their own tables, see DbDao.RegisterUndoModel
+func (d *DbDao) DB() *gorm.DB {
+	return d.db
+}
+
+// RegisterUndoModel journals the writes of a table of another service through the DbDao like the ones of das_database,
+// so that they are reverted by RollbackBlock. It must be called before the parser runs.
+func (d *DbDao) RegisterUndoModel(table string, newModel func() interface{}) {
+	d.undoModels[table] = newModel
+}
+
+func registerRowChangeCallbacks(db *gorm.DB) error {
+	if err := db.Callback().Create().After("gorm:create").Register("das:row_change_after_create", rowChangeAfterCreate); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("das:row_change_before_update", rowChangeBeforeWhere); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:update").Register("das:row_change_after_update", rowChangeAfterUpdate); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("gorm:delete").Register("das:row_change_before_delete", rowChangeBeforeWhere); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().After("gorm:delete").Register("das:row_change_after_delete", rowChangeAfterDelete); err != nil {
+		return err
+	}
+	return nil
+}
+
+const rowChangeInstanceKeyRows = "das:row_change_rows"
+
+func rowCollector(db *gorm.DB) (*RowCollector, bool) {
+	if db.Error != nil || db.DryRun || db.Statement.Schema == nil || db.Statement.Context == nil ||
+		db.Statement.Table == TableNameBlockUndoInfo {
+		return nil, false
+	}
+	c, ok := db.Statement.Context.Value(ctxKeyRowCollector{}).(*RowCollector)
+	return c, ok && c != nil
+}
+
+// remember the rows matched by the update / delete conditions
+func rowChangeBeforeWhere(db *gorm.DB) {
+	if _, ok := rowCollector(db); !ok {
+		return
+	}
+	where, ok := db.Statement.Clauses["WHERE"]
+	if !ok {
+		return
+	}
+	rows := reflect.New(reflect.SliceOf(db.Statement.Schema.ModelType))
+	if err := db.Session(&gorm.Session{NewDB: true}).Table(db.Statement.Table).
+		Clauses(where.Expression).Find(rows.Interface()).Error; err != nil {
+		_ = db.AddError(fmt.Errorf("row change find err: %s", err.Error()))
+		return
+	}
+	db.InstanceSet(rowChangeInstanceKeyRows, rows.Elem())
+}
+
+func rowChangeAfterCreate(db *gorm.DB) {
+	c, ok := rowCollector(db)
+	if !ok {
+		return
+	}
+	uk := uniqueIndexFields(db.Statement.Schema)
+	var list []RowChange
+	for _, v := range undoReflectValues(db.Statement.ReflectValue) {
+		// upserted rows are read back by unique index, their values may differ from the inserted ones
+		if len(uk) > 0 {
+			row := reflect.New(db.Statement.Schema.ModelType)
+			if err := db.Session(&gorm.Session{NewDB: true}).Table(db.Statement.Table).
+				Where(uniqueIndexConditions(db.Statement.Context, uk, v)).Limit(1).Find(row.Interface()).Error; err != nil {
+				_ = db.AddError(fmt.Errorf("row change find err: %s", err.Error()))
+				return
+			}
+			v = row.Elem()
+		}
+		list = append(list, RowChange{Table: db.Statement.Table, Operation: RowOperationUpsert, Row: v.Interface()})
+	}
+	c.add(list...)
+}
+
+func rowChangeAfterUpdate(db *gorm.DB) {
+	c, ok := rowCollector(db)
+	if !ok {
+		return
+	}
+	value, ok := db.InstanceGet(rowChangeInstanceKeyRows)
+	if !ok {
+		return
+	}
+	before := value.(reflect.Value)
+	if before.Len() == 0 {
+		return
+	}
+	pk := db.Statement.Schema.PrioritizedPrimaryField
+	var ids []interface{}
+	for i := 0; i < before.Len(); i++ {
+		id, _ := pk.ValueOf(db.Statement.Context, before.Index(i))
+		ids = append(ids, id)
+	}
+	rows := reflect.New(reflect.SliceOf(db.Statement.Schema.ModelType))
+	if err := db.Session(&gorm.Session{NewDB: true}).Table(db.Statement.Table).
+		Where(pk.DBName+" IN ?", ids).Find(rows.Interface()).Error; err != nil {
+		_ = db.AddError(fmt.Errorf("row change find err: %s", err.Error()))
+		return
+	}
+	c.add(rowChanges(db.Statement.Table, RowOperationUpsert, rows.Elem())...)
+}
+
+func rowChangeAfterDelete(db *gorm.DB) {
+	c, ok := rowCollector(db)
+	if !ok {
+		return
+	}
+	value, ok := db.InstanceGet(rowChangeInstanceKeyRows)
+	if !ok {
+		return
+	}
+	c.add(rowChanges(db.Statement.Table, RowOperationDelete, value.(reflect.Value))...)
+}
+
+func rowChanges(table, operation string, rows reflect.Value) []RowChange {
+	list := make([]RowChange, 0, rows.Len())
+	for i := 0; i < rows.Len(); i++ {
+		list = append(list, RowChange{Table: table, Operation: operation, Row: rows.Index(i).Interface()})
+	}
+	return list
+}
@@ -26,7 +26,7 @@ func (t *TableSmtInfo) TableName() string {
 }
 
 func (d *DbDao) CreateSubAccount(subAccountIds []string, accountInfos []TableAccountInfo, smtInfos []TableSmtInfo, transactionInfo TableTransactionInfo, parentAccountInfo TableAccountInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if len(subAccountIds) > 0 {
 			if err := tx.Where(" account_id IN(?) ", subAccountIds).
 				Delete(&TableRecordsInfo{}).Error; err != nil {
@@ -79,7 +79,7 @@ func (d *DbDao) CreateSubAccount(subAccountIds []string, accountInfos []TableAcc
 }
 
 func (d *DbDao) EditOwnerSubAccount(accountInfo TableAccountInfo, smtInfo TableSmtInfo, transactionInfo TableTransactionInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if err := tx.Select("block_number", "outpoint",
 			"owner_chain_type", "owner", "owner_algorithm_id",
 			"manager_chain_type", "manager", "manager_algorithm_id", "nonce").
@@ -111,7 +111,7 @@ func (d *DbDao) EditOwnerSubAccount(accountInfo TableAccountInfo, smtInfo TableS
 	})
 }
 func (d *DbDao) EditManagerSubAccount(accountInfo TableAccountInfo, smtInfo TableSmtInfo, transactionInfo TableTransactionInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if err := tx.Select("block_number", "outpoint",
 			"manager_chain_type", "manager", "manager_algorithm_id", "nonce").
 			Where("account_id = ?", accountInfo.AccountId).
@@ -138,7 +138,7 @@ func (d *DbDao) EditManagerSubAccount(accountInfo TableAccountInfo, smtInfo Tabl
 	})
 }
 func (d *DbDao) EditRecordsSubAccount(accountInfo TableAccountInfo, smtInfo TableSmtInfo, transactionInfo TableTransactionInfo, recordsInfos []TableRecordsInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if err := tx.Select("block_number", "outpoint", "nonce").
 			Where("account_id = ?", accountInfo.AccountId).
 			Updates(accountInfo).Error; err != nil {
@@ -174,8 +174,43 @@ func (d *DbDao) EditRecordsSubAccount(accountInfo TableAccountInfo, smtInfo Tabl
 	})
 }
 
+func (d *DbDao) SubAccountCrossChain(accountInfo TableAccountInfo, smtInfo TableSmtInfo, transactionInfo TableTransactionInfo, isTrans bool) error {
+	return d.transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("block_number", "outpoint",
+			"owner_chain_type", "owner", "owner_algorithm_id",
+			"manager_chain_type", "manager", "manager_algorithm_id", "status", "nonce").
+			Where("account_id = ?", accountInfo.AccountId).
+			Updates(accountInfo).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Select("block_number", "outpoint", "leaf_data_hash").
+			Where("account_id = ?", accountInfo.AccountId).
+			Updates(&smtInfo).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{
+				"account_id", "account", "service_type",
+				"chain_type", "address", "capacity", "status",
+			}),
+		}).Create(&transactionInfo).Error; err != nil {
+			return err
+		}
+
+		if isTrans {
+			if err := tx.Where("account_id = ?", accountInfo.AccountId).Delete(&TableRecordsInfo{}).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
 func (d *DbDao) RenewSubAccount(accountInfos []TableAccountInfo, smtInfos []TableSmtInfo, transactionInfos []TableTransactionInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if len(accountInfos) > 0 {
 			if err := tx.Clauses(clause.OnConflict{
 				DoUpdates: clause.AssignmentColumns([]string{
@@ -212,7 +247,7 @@ func (d *DbDao) RenewSubAccount(accountInfos []TableAccountInfo, smtInfos []Tabl
 }
 
 func (d *DbDao) RecycleSubAccount(accountIds []string, transactionInfos []TableTransactionInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if err := tx.Where("account_id IN(?)", accountIds).Delete(&TableAccountInfo{}).Error; err != nil {
 			return err
 		}
@@ -16,7 +16,7 @@ func getInit() (*DbDao, error) {
 	if err != nil {
 		return nil, fmt.Errorf("NewGormDataBase err:%s", err.Error())
 	}
-	dbDao, err := Initialize(db)
+	dbDao, err := Initialize(db, config.Cfg.NetworkName())
 	if err != nil {
 		return nil, fmt.Errorf("Initialize err:%s ", err.Error())
 	}
@@ -251,6 +251,32 @@ func TestFindBlockInfoByBlockNumber(t *testing.T) {
 	t.Log(blockInfo)
 }
 
+func TestRollbackBlock(t *testing.T) {
+	dbDao, err := getInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	incomeCellInfo := TableIncomeCellInfo{
+		BlockNumber:    57181893,
+		Action:         "create_income",
+		Outpoint:       "0xe9116d651c371662b6e29e2102422e23f90656b8619df82c48b782ff4db43a37_4",
+		Capacity:       40000000000,
+		BlockTimestamp: 1635320117861,
+	}
+	if err = dbDao.WithBlockNumber(57181893).CreateIncomeCellInfo(incomeCellInfo); err != nil {
+		t.Fatal(err)
+	}
+	if err = dbDao.RollbackBlock(57181893); err != nil {
+		t.Fatal(err)
+	}
+	info, err := dbDao.FirstIncomeCellInfoByOutpoint(incomeCellInfo.Outpoint)
+	if err != nil {
+		t.Fatal(err)
+	} else if info.Id > 0 {
+		t.Fatal("income cell not rolled back")
+	}
+}
+
 func TestUpdateCNYToUSDRate(t *testing.T) {
 	dbDao, err := getInit()
 	if err != nil {
@@ -280,8 +306,45 @@ func TestAutoMigrate(t *testing.T) {
 		&TableTradeDealInfo{},
 		&TableTradeInfo{},
 		&TableTransactionInfo{},
+		&TableBlockUndoInfo{},
+		&TableDeadLetterInfo{},
+		&TableAccountHistory{},
+		&TableRecordsHistory{},
+		&TableCrossChainInfo{},
+		&TableEventOutbox{},
+		&TableChangeInfo{},
+		&TableVerifyReport{},
+		&TablePendingTx{},
+		&TableProvisionalChange{},
 	)
 	if err != nil {
 		t.Fatal(err)
 	}
 }
+
+func TestApplyProvisionalChanges(t *testing.T) {
+	list := []TableRecordsInfo{{Id: 1, Key: "60"}, {Id: 2, Key: "195"}}
+	changes, err := ProvisionalChangeList(10, "0x01", "0x02", "edit_records", []RowChange{
+		{Table: TableNameRecordsInfo, Operation: RowOperationDelete, Row: list[0]},
+		{Table: TableNameRecordsInfo, Operation: RowOperationUpsert, Row: TableRecordsInfo{Id: 2, Key: "0"}},
+		{Table: TableNameRecordsInfo, Operation: RowOperationUpsert, Row: TableRecordsInfo{Id: 3, Key: "twitter"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = applyProvisionalChanges(changes, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Id != 2 || list[0].Key != "0" || list[1].Id != 3 || list[1].Key != "twitter" {
+		t.Fatal(list)
+	}
+}
+
+func TestCallerFuncName(t *testing.T) {
+	name := func() string {
+		return callerFuncName(2)
+	}()
+	if name != "TestCallerFuncName" {
+		t.Fatal(name)
+	}
+}
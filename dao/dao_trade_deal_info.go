@@ -34,3 +34,14 @@ const (
 func (t *TableTradeDealInfo) TableName() string {
 	return TableNameTradeDealInfo
 }
+
+func (d *DbDao) FindRecentDealList(limit, offset int) (list []TableTradeDealInfo, err error) {
+	err = d.db.Order("block_number DESC, id DESC").Limit(limit).Offset(offset).Find(&list).Error
+	return
+}
+
+func (d *DbDao) FindDealListByAccountId(accountId string, limit, offset int) (list []TableTradeDealInfo, err error) {
+	err = d.db.Where("account_id = ?", accountId).
+		Order("block_number DESC, id DESC").Limit(limit).Offset(offset).Find(&list).Error
+	return
+}
@@ -45,7 +45,7 @@ type SaleAccount struct {
 }
 
 func (d *DbDao) StartAccountSale(accountInfo TableAccountInfo, tradeInfo TableTradeInfo, tradeHistory TableTradeHistoryInfo, transactionInfo TableTransactionInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if err := tx.Select("block_number", "manager", "manager_chain_type", "manager_algorithm_id", "owner", "owner_algorithm_id", "owner_chain_type", "outpoint", "status").
 			Where("account_id = ?", accountInfo.AccountId).
 			Updates(accountInfo).Error; err != nil {
@@ -81,7 +81,7 @@ func (d *DbDao) StartAccountSale(accountInfo TableAccountInfo, tradeInfo TableTr
 }
 
 func (d *DbDao) EditAccountSale(tradeInfo TableTradeInfo, tradeHistory TableTradeHistoryInfo, transactionInfo TableTransactionInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if err := tx.Select("block_number", "outpoint", "description", "block_timestamp", "price_ckb", "price_usd", "profit_rate").
 			Where("account_id = ?", tradeInfo.AccountId).
 			Updates(tradeInfo).Error; err != nil {
@@ -108,7 +108,7 @@ func (d *DbDao) EditAccountSale(tradeInfo TableTradeInfo, tradeHistory TableTrad
 }
 
 func (d *DbDao) CancelAccountSale(accountInfo TableAccountInfo, transactionInfo TableTransactionInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if err := tx.Select("block_number", "outpoint", "status").
 			Where("account_id = ?", accountInfo.AccountId).
 			Updates(accountInfo).Error; err != nil {
@@ -133,7 +133,7 @@ func (d *DbDao) CancelAccountSale(accountInfo TableAccountInfo, transactionInfo
 }
 
 func (d *DbDao) BuyAccount(incomeCellInfos []TableIncomeCellInfo, accountInfo TableAccountInfo, dealInfo TableTradeDealInfo, transactionInfoBuy, transactionInfoSale TableTransactionInfo, rebateInfos []TableRebateInfo, recordsInfos []TableRecordsInfo) error {
-	return d.db.Transaction(func(tx *gorm.DB) error {
+	return d.transaction(func(tx *gorm.DB) error {
 		if len(incomeCellInfos) > 0 {
 			if err := tx.Clauses(clause.OnConflict{
 				DoUpdates: clause.AssignmentColumns([]string{
@@ -206,3 +206,66 @@ func (d *DbDao) BuyAccount(incomeCellInfos []TableIncomeCellInfo, accountInfo Ta
 		return nil
 	})
 }
+
+const (
+	TradeSortByPrice     = "price"
+	TradeSortByStartedAt = "started_at"
+)
+
+type TradeListFilter struct {
+	PriceCkbMin   uint64
+	PriceCkbMax   uint64
+	PriceUsdMin   decimal.Decimal
+	PriceUsdMax   decimal.Decimal
+	CharsetNum    uint64
+	AccountLenMin int // length without the .bit suffix
+	AccountLenMax int
+	SortBy        string
+	Desc          bool
+}
+
+func (d *DbDao) tradeListQuery(filter TradeListFilter) *gorm.DB {
+	db := d.db.Model(&TableTradeInfo{}).Where("t_trade_info.status = ?", AccountStatusOnSale)
+	if filter.PriceCkbMin > 0 {
+		db = db.Where("t_trade_info.price_ckb >= ?", filter.PriceCkbMin)
+	}
+	if filter.PriceCkbMax > 0 {
+		db = db.Where("t_trade_info.price_ckb <= ?", filter.PriceCkbMax)
+	}
+	if filter.PriceUsdMin.IsPositive() {
+		db = db.Where("t_trade_info.price_usd >= ?", filter.PriceUsdMin)
+	}
+	if filter.PriceUsdMax.IsPositive() {
+		db = db.Where("t_trade_info.price_usd <= ?", filter.PriceUsdMax)
+	}
+	if filter.AccountLenMin > 0 {
+		db = db.Where("CHAR_LENGTH(t_trade_info.account)-4 >= ?", filter.AccountLenMin)
+	}
+	if filter.AccountLenMax > 0 {
+		db = db.Where("CHAR_LENGTH(t_trade_info.account)-4 <= ?", filter.AccountLenMax)
+	}
+	if filter.CharsetNum > 0 {
+		db = db.Joins("JOIN t_account_info a ON a.account_id = t_trade_info.account_id").
+			Where("a.charset_num = ?", filter.CharsetNum)
+	}
+	return db
+}
+
+func (d *DbDao) FindTradeList(filter TradeListFilter, limit, offset int) (list []TableTradeInfo, err error) {
+	order := "t_trade_info.started_at"
+	if filter.SortBy == TradeSortByPrice {
+		order = "t_trade_info.price_usd"
+	}
+	if filter.Desc {
+		order += " DESC"
+	}
+	err = d.tradeListQuery(filter).Select("t_trade_info.*").
+		Order(order).Order("t_trade_info.id").
+		Limit(limit).Offset(offset).Find(&list).Error
+	return
+}
+
+func (d *DbDao) GetTradeCount(filter TradeListFilter) (count int64, err error) {
+	err = d.tradeListQuery(filter).Count(&count).Error
+	return
+}
@@ -7,14 +7,14 @@ import (
 )
 
 type TableTransactionInfo struct {
-	Id             uint64           `json:"id" gorm:"column:id;primaryKey;type:bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT ''"`
-	BlockNumber    uint64           `json:"block_number" gorm:"column:block_number;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT ''"`
-	AccountId      string           `json:"account_id" gorm:"account_id;index:k_ai_a;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'hash of account'"`
+	Id             uint64           `json:"id" gorm:"column:id;primaryKey;index:k_ai_bn_id,priority:3;index:k_ct_a_bn_id,priority:4;type:bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT ''"`
+	BlockNumber    uint64           `json:"block_number" gorm:"column:block_number;index:k_ai_bn_id,priority:2;index:k_ct_a_bn_id,priority:3;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT ''"`
+	AccountId      string           `json:"account_id" gorm:"account_id;index:k_ai_a;index:k_ai_bn_id,priority:1;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'hash of account'"`
 	Account        string           `json:"account" gorm:"column:account;index:k_a_a;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
 	Action         string           `json:"action" gorm:"column:action;index:k_ct_a_a,priority:3;index:k_a_a;index:k_ai_a;uniqueIndex:uk_a_o;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
 	ServiceType    int              `json:"service_type" gorm:"column:service_type;type:smallint(6) NOT NULL DEFAULT '0' COMMENT '1: register 2: trade'"`
-	ChainType      common.ChainType `json:"chain_type" gorm:"column:chain_type;index:k_ct_a_a,priority:1;index:k_ct_a;type:smallint(6) NOT NULL DEFAULT '0' COMMENT ''"`
-	Address        string           `json:"address" gorm:"column:address;index:k_ct_a_a,priority:2;index:k_ct_a;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	ChainType      common.ChainType `json:"chain_type" gorm:"column:chain_type;index:k_ct_a_a,priority:1;index:k_ct_a;index:k_ct_a_bn_id,priority:1;type:smallint(6) NOT NULL DEFAULT '0' COMMENT ''"`
+	Address        string           `json:"address" gorm:"column:address;index:k_ct_a_a,priority:2;index:k_ct_a;index:k_ct_a_bn_id,priority:2;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
 	Capacity       uint64           `json:"capacity" gorm:"column:capacity;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT ''"`
 	Outpoint       string           `json:"outpoint" gorm:"column:outpoint;index:k_outpoint;uniqueIndex:uk_a_o;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
 	BlockTimestamp uint64           `json:"block_timestamp" gorm:"column:block_timestamp;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT ''"`
@@ -80,3 +80,36 @@ func (d *DbDao) FindTransactionInfoByAccountAction(account, action string) (tran
 	err = d.db.Where("account = ? AND action = ?", account, action).Limit(1).Find(&transactionInfo).Error
 	return
 }
+
+type TransactionListFilter struct {
+	AccountId   string
+	ChainType   common.ChainType
+	Address     string
+	Actions     []string
+	ServiceType int
+	// keyset cursor, rows after (CursorBlockNumber, CursorId) in descending order
+	CursorBlockNumber uint64
+	CursorId          uint64
+}
+
+// FindTransactionList pages by the keyset (block_number, id), served by k_ai_bn_id and k_ct_a_bn_id
+func (d *DbDao) FindTransactionList(filter TransactionListFilter, limit int) (list []TableTransactionInfo, err error) {
+	db := d.db
+	if filter.AccountId != "" {
+		db = db.Where("account_id = ?", filter.AccountId)
+	} else {
+		db = db.Where("chain_type = ? AND address = ?", filter.ChainType, filter.Address)
+	}
+	if len(filter.Actions) > 0 {
+		db = db.Where("action IN ?", filter.Actions)
+	}
+	if filter.ServiceType > 0 {
+		db = db.Where("service_type = ?", filter.ServiceType)
+	}
+	if filter.CursorId > 0 {
+		db = db.Where("(block_number < ? OR (block_number = ? AND id < ?))",
+			filter.CursorBlockNumber, filter.CursorBlockNumber, filter.CursorId)
+	}
+	err = db.Order("block_number DESC, id DESC").Limit(limit).Find(&list).Error
+	return
+}
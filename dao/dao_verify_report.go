@@ -0,0 +1,100 @@
+package dao
+
+import (
+	"gorm.io/gorm"
+	"math/rand"
+	"time"
+)
+
+type TableVerifyReport struct {
+	Id         uint64    `json:"id" gorm:"column:id;primaryKey;type:bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT ''"`
+	RunId      string    `json:"run_id" gorm:"column:run_id;index:k_run_id;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'verifier run'"`
+	CheckTable string    `json:"check_table" gorm:"column:check_table;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'table of the row'"`
+	RowId      uint64    `json:"row_id" gorm:"column:row_id;type:bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT 'id of the row'"`
+	AccountId  string    `json:"account_id" gorm:"column:account_id;index:k_account_id;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'hash of account'"`
+	Account    string    `json:"account" gorm:"column:account;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT ''"`
+	Outpoint   string    `json:"outpoint" gorm:"column:outpoint;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'outpoint of the row'"`
+	Field      string    `json:"field" gorm:"column:field;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'live, owner, manager, status, expired_at, records ...'"`
+	DbValue    string    `json:"db_value" gorm:"column:db_value;type:text CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci COMMENT ''"`
+	ChainValue string    `json:"chain_value" gorm:"column:chain_value;type:text CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci COMMENT ''"`
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at;type:timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT ''"`
+}
+
+const (
+	TableNameVerifyReport = "t_verify_report"
+)
+
+func (t *TableVerifyReport) TableName() string {
+	return TableNameVerifyReport
+}
+
+func (d *DbDao) CreateVerifyReport(list []TableVerifyReport) error {
+	if len(list) == 0 {
+		return nil
+	}
+	return d.db.Create(&list).Error
+}
+
+func (d *DbDao) FindVerifyReportList(runId string, limit, offset int) (list []TableVerifyReport, err error) {
+	err = d.db.Where("run_id = ?", runId).Order("id").Limit(limit).Offset(offset).Find(&list).Error
+	return
+}
+
+// verifyListQuery scans the rows of model by id after lastId,
+// or if sample the limit rows from a random id, picked below MAX(id) so that the index on id serves it
+func (d *DbDao) verifyListQuery(model interface{}, sample bool, lastId uint64, limit int) (*gorm.DB, error) {
+	if !sample {
+		return d.db.Where("id > ?", lastId).Order("id").Limit(limit), nil
+	}
+	var maxId uint64
+	if err := d.db.Model(model).Select("IFNULL(MAX(id), 0)").Scan(&maxId).Error; err != nil {
+		return nil, err
+	}
+	startId := uint64(1)
+	if maxId > uint64(limit) {
+		startId += uint64(rand.New(rand.NewSource(time.Now().UnixNano())).Int63n(int64(maxId - uint64(limit) + 1)))
+	}
+	return d.db.Where("id >= ?", startId).Order("id").Limit(limit), nil
+}
+
+// FindVerifyAccountList returns the accounts (not the sub-accounts, which live in the smt) to verify
+func (d *DbDao) FindVerifyAccountList(sample bool, lastId uint64, limit int) (list []TableAccountInfo, err error) {
+	db, err := d.verifyListQuery(&TableAccountInfo{}, sample, lastId, limit)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Where("parent_account_id = ''").Find(&list).Error
+	return
+}
+
+func (d *DbDao) FindVerifyOfferList(sample bool, lastId uint64, limit int) (list []TableOfferInfo, err error) {
+	db, err := d.verifyListQuery(&TableOfferInfo{}, sample, lastId, limit)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Find(&list).Error
+	return
+}
+
+func (d *DbDao) FindVerifyTradeList(sample bool, lastId uint64, limit int) (list []TableTradeInfo, err error) {
+	db, err := d.verifyListQuery(&TableTradeInfo{}, sample, lastId, limit)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Find(&list).Error
+	return
+}
+
+func (d *DbDao) FindVerifyReverseList(sample bool, lastId uint64, limit int) (list []TableReverseInfo, err error) {
+	db, err := d.verifyListQuery(&TableReverseInfo{}, sample, lastId, limit)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Find(&list).Error
+	return
+}
+
+// FindVerifyRow reloads a row of the verified tables into dest, whose id is 0 if the row is gone
+func (d *DbDao) FindVerifyRow(dest interface{}, id uint64) error {
+	return d.db.Where("id = ?", id).Limit(1).Find(dest).Error
+}
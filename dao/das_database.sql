@@ -404,4 +404,269 @@ CREATE TABLE `t_custom_script_info`
     UNIQUE KEY `uk_account_id` (`account_id`) USING BTREE
 ) ENGINE = InnoDB
   DEFAULT CHARSET = utf8mb4
-  COLLATE = utf8mb4_0900_ai_ci COMMENT ='custom script info';
\ No newline at end of file
+  COLLATE = utf8mb4_0900_ai_ci COMMENT ='custom script info';
+
+-- ----------------------------
+-- Table structure for t_block_undo_info
+-- ----------------------------
+DROP TABLE IF EXISTS `t_block_undo_info`;
+CREATE TABLE `t_block_undo_info`
+(
+    `id`           bigint(20) unsigned                                           NOT NULL AUTO_INCREMENT COMMENT '',
+    `block_number` bigint(20) unsigned                                           NOT NULL DEFAULT '0' COMMENT '',
+    `undo_table`   varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'table written by the block',
+    `undo_type`    smallint(6)                                                   NOT NULL DEFAULT '0' COMMENT '1: delete row 2: restore row',
+    `row_id`       bigint(20) unsigned                                           NOT NULL DEFAULT '0' COMMENT 'primary key of the row',
+    `row_data`     mediumtext CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci   COMMENT 'row before the block, json',
+    `created_at`   timestamp                                                     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '',
+    `updated_at`   timestamp                                                     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '',
+    PRIMARY KEY (`id`) USING BTREE,
+    KEY `k_block_number` (`block_number`) USING BTREE
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_ai_ci COMMENT ='writes of the recent blocks, reverted on chain reorg';
+
+-- t_transaction_info keyset of FindTransactionList
+ALTER TABLE `t_transaction_info`
+    ADD KEY `k_ai_bn_id` (`account_id`, `block_number`, `id`) USING BTREE,
+    ADD KEY `k_ct_a_bn_id` (`chain_type`, `address`, `block_number`, `id`) USING BTREE;
+
+-- ----------------------------
+-- Table structure for t_dead_letter_info
+-- ----------------------------
+DROP TABLE IF EXISTS `t_dead_letter_info`;
+CREATE TABLE `t_dead_letter_info`
+(
+    `id`              bigint(20) unsigned                                           NOT NULL AUTO_INCREMENT COMMENT '',
+    `block_number`    bigint(20) unsigned                                           NOT NULL DEFAULT '0' COMMENT '',
+    `block_timestamp` bigint(20) unsigned                                           NOT NULL DEFAULT '0' COMMENT '',
+    `tx_hash`         varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `action`          varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `err_msg`         text CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci         COMMENT 'error of the last failure',
+    `retry_count`     int(11)                                                       NOT NULL DEFAULT '0' COMMENT 'failures before quarantined',
+    `status`          smallint(6)                                                   NOT NULL DEFAULT '0' COMMENT '0: quarantined 1: released 2: processed',
+    `created_at`      timestamp                                                     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '',
+    `updated_at`      timestamp                                                     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '',
+    PRIMARY KEY (`id`) USING BTREE,
+    KEY `k_block_number` (`block_number`) USING BTREE,
+    UNIQUE KEY `uk_tx_hash` (`tx_hash`) USING BTREE,
+    KEY `k_status` (`status`) USING BTREE
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_ai_ci COMMENT ='txs quarantined by the parser policy';
+
+-- ----------------------------
+-- Table structure for t_account_history
+-- ----------------------------
+DROP TABLE IF EXISTS `t_account_history`;
+CREATE TABLE `t_account_history`
+(
+    `id`                   bigint(20) unsigned                                           NOT NULL AUTO_INCREMENT COMMENT '',
+    `block_number`         bigint(20) unsigned                                           NOT NULL DEFAULT '0' COMMENT '',
+    `block_timestamp`      bigint(20) unsigned                                           NOT NULL DEFAULT '0' COMMENT '',
+    `account_id`           varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'hash of account',
+    `account`              varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `tx_hash`              varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `action`               varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `operation`            varchar(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci  NOT NULL DEFAULT '' COMMENT 'create, update, delete or seed',
+    `outpoint`             varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `owner_chain_type`     smallint(6)                                                   NOT NULL DEFAULT '0' COMMENT '',
+    `owner`                varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'owner address',
+    `owner_algorithm_id`   smallint(6)                                                   NOT NULL DEFAULT '0' COMMENT '',
+    `manager_chain_type`   smallint(6)                                                   NOT NULL DEFAULT '0' COMMENT '',
+    `manager`              varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'manager address',
+    `manager_algorithm_id` smallint(6)                                                   NOT NULL DEFAULT '0' COMMENT '',
+    `status`               smallint(6)                                                   NOT NULL DEFAULT '0' COMMENT '',
+    `registered_at`        bigint(20) unsigned                                           NOT NULL DEFAULT '0' COMMENT '',
+    `expired_at`           bigint(20) unsigned                                           NOT NULL DEFAULT '0' COMMENT '',
+    `created_at`           timestamp                                                     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '',
+    `updated_at`           timestamp                                                     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '',
+    PRIMARY KEY (`id`) USING BTREE,
+    KEY `k_ai_bn` (`account_id`, `block_number`) USING BTREE,
+    KEY `k_ai_bt` (`account_id`, `block_timestamp`) USING BTREE
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_ai_ci COMMENT ='account state per tx';
+
+-- ----------------------------
+-- Table structure for t_records_history
+-- ----------------------------
+DROP TABLE IF EXISTS `t_records_history`;
+CREATE TABLE `t_records_history`
+(
+    `id`              bigint(20) unsigned                                            NOT NULL AUTO_INCREMENT COMMENT '',
+    `block_number`    bigint(20) unsigned                                            NOT NULL DEFAULT '0' COMMENT '',
+    `block_timestamp` bigint(20) unsigned                                            NOT NULL DEFAULT '0' COMMENT '',
+    `tx_hash`         varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci  NOT NULL DEFAULT '' COMMENT '',
+    `action`          varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci  NOT NULL DEFAULT '' COMMENT '',
+    `account_id`      varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci  NOT NULL DEFAULT '' COMMENT 'hash of account',
+    `account`         varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci  NOT NULL DEFAULT '' COMMENT '',
+    `operation`       varchar(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci   NOT NULL DEFAULT '' COMMENT 'add, change or remove',
+    `key`             varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci  NOT NULL DEFAULT '',
+    `type`            varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci  NOT NULL DEFAULT '',
+    `label`           varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci  NOT NULL DEFAULT '',
+    `value`           varchar(1024) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'value set by the tx',
+    `ttl`             varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci  NOT NULL DEFAULT '',
+    `old_value`       varchar(1024) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'value before the tx',
+    `old_ttl`         varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci  NOT NULL DEFAULT '',
+    `created_at`      timestamp                                                      NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '',
+    `updated_at`      timestamp                                                      NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '',
+    PRIMARY KEY (`id`) USING BTREE,
+    KEY `k_ai_bn` (`account_id`, `block_number`) USING BTREE
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_ai_ci COMMENT ='records edits per tx';
+
+-- ----------------------------
+-- Table structure for t_cross_chain_info
+-- ----------------------------
+DROP TABLE IF EXISTS `t_cross_chain_info`;
+CREATE TABLE `t_cross_chain_info`
+(
+    `id`                      bigint(20) unsigned                                           NOT NULL AUTO_INCREMENT COMMENT '',
+    `account_id`              varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'hash of account',
+    `account`                 varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `coin_type`               varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'slip44 coin type of the target chain',
+    `chain_id`                varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `owner_chain_type`        smallint(6)                                                   NOT NULL DEFAULT '0' COMMENT '',
+    `owner`                   varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'owner when locked',
+    `lock_block_number`       bigint(20) unsigned                                           NOT NULL DEFAULT '0' COMMENT '',
+    `lock_timestamp`          bigint(20) unsigned                                           NOT NULL DEFAULT '0' COMMENT '',
+    `lock_tx_hash`            varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `unlock_block_number`     bigint(20) unsigned                                           NOT NULL DEFAULT '0' COMMENT '',
+    `unlock_timestamp`        bigint(20) unsigned                                           NOT NULL DEFAULT '0' COMMENT '',
+    `unlock_tx_hash`          varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `unlock_owner_chain_type` smallint(6)                                                   NOT NULL DEFAULT '0' COMMENT '',
+    `unlock_owner`            varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'owner when unlocked',
+    `is_trans`                tinyint(1)                                                    NOT NULL DEFAULT '0' COMMENT 'owner changed on unlock',
+    `status`                  smallint(6)                                                   NOT NULL DEFAULT '0' COMMENT '0: locked 1: unlocked',
+    `created_at`              timestamp                                                     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '',
+    `updated_at`              timestamp                                                     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '',
+    PRIMARY KEY (`id`) USING BTREE,
+    UNIQUE KEY `uk_ai_lth` (`account_id`, `lock_tx_hash`) USING BTREE,
+    KEY `k_status` (`status`) USING BTREE
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_ai_ci COMMENT ='cross-chain locks of the accounts';
+
+-- ----------------------------
+-- Table structure for t_event_outbox
+-- ----------------------------
+DROP TABLE IF EXISTS `t_event_outbox`;
+CREATE TABLE `t_event_outbox`
+(
+    `id`              bigint(20) unsigned                                           NOT NULL AUTO_INCREMENT COMMENT 'event id',
+    `event_type`      varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `block_number`    bigint(20) unsigned                                           NOT NULL DEFAULT '0' COMMENT '',
+    `block_timestamp` bigint(20) unsigned                                           NOT NULL DEFAULT '0' COMMENT '',
+    `tx_hash`         varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `action`          varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `account_id`      varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'hash of account',
+    `account`         varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `revert_of`       bigint(20) unsigned                                           NOT NULL DEFAULT '0' COMMENT 'id of the event reverted by an EventReverted',
+    `payload`         mediumtext CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci   COMMENT 'rows written by the tx, json',
+    `status`          smallint(6)                                                   NOT NULL DEFAULT '0' COMMENT '0: pending 1: published 2: reverted',
+    `created_at`      timestamp                                                     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '',
+    `updated_at`      timestamp                                                     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '',
+    PRIMARY KEY (`id`) USING BTREE,
+    KEY `k_block_number` (`block_number`) USING BTREE,
+    KEY `k_status` (`status`) USING BTREE
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_ai_ci COMMENT ='domain events to publish';
+
+-- ----------------------------
+-- Table structure for t_change_info
+-- ----------------------------
+DROP TABLE IF EXISTS `t_change_info`;
+CREATE TABLE `t_change_info`
+(
+    `id`           bigint(20) unsigned                                            NOT NULL AUTO_INCREMENT COMMENT 'cursor of the change feed',
+    `block_number` bigint(20) unsigned                                            NOT NULL DEFAULT '0' COMMENT '',
+    `tx_hash`      varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci  NOT NULL DEFAULT '' COMMENT 'empty for the changes of a rollback',
+    `action`       varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci  NOT NULL DEFAULT '' COMMENT '',
+    `change_table` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci  NOT NULL DEFAULT '' COMMENT '',
+    `row_key`      varchar(1024) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'unique index (or primary key) of the row, json',
+    `operation`    varchar(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci   NOT NULL DEFAULT '' COMMENT 'upsert or delete',
+    `row_data`     mediumtext CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci    COMMENT 'row after an upsert, json',
+    `created_at`   timestamp                                                      NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '',
+    PRIMARY KEY (`id`) USING BTREE,
+    KEY `k_block_number` (`block_number`) USING BTREE
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_ai_ci COMMENT ='row-level change feed';
+
+-- ----------------------------
+-- Table structure for t_verify_report
+-- ----------------------------
+DROP TABLE IF EXISTS `t_verify_report`;
+CREATE TABLE `t_verify_report`
+(
+    `id`          bigint(20) unsigned                                           NOT NULL AUTO_INCREMENT COMMENT '',
+    `run_id`      varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'verifier run',
+    `check_table` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'table of the row',
+    `row_id`      bigint(20) unsigned                                           NOT NULL DEFAULT '0' COMMENT 'id of the row',
+    `account_id`  varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'hash of account',
+    `account`     varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `outpoint`    varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'outpoint of the row',
+    `field`       varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'live, owner, manager, status, expired_at, records ...',
+    `db_value`    text CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci         COMMENT '',
+    `chain_value` text CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci         COMMENT '',
+    `created_at`  timestamp                                                     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '',
+    PRIMARY KEY (`id`) USING BTREE,
+    KEY `k_run_id` (`run_id`) USING BTREE,
+    KEY `k_account_id` (`account_id`) USING BTREE
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_ai_ci COMMENT ='discrepancies found by the verifier';
+
+-- ----------------------------
+-- Table structure for t_pending_tx
+-- ----------------------------
+DROP TABLE IF EXISTS `t_pending_tx`;
+CREATE TABLE `t_pending_tx`
+(
+    `id`            bigint(20) unsigned                                           NOT NULL AUTO_INCREMENT COMMENT '',
+    `tx_hash`       varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `action`        varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `account_id`    varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'hash of account',
+    `account`       varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `chain_type`    smallint(6)                                                   NOT NULL DEFAULT '0' COMMENT 'owner of the first das-lock input',
+    `address`       varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `to_chain_type` smallint(6)                                                   NOT NULL DEFAULT '0' COMMENT 'owner of the first das-lock output',
+    `to_address`    varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `status`        smallint(6)                                                   NOT NULL DEFAULT '0' COMMENT '0: pending 1: committed 2: rejected',
+    `block_number`  bigint(20) unsigned                                           NOT NULL DEFAULT '0' COMMENT 'block of the committed tx',
+    `created_at`    timestamp                                                     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '',
+    `updated_at`    timestamp                                                     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '',
+    PRIMARY KEY (`id`) USING BTREE,
+    UNIQUE KEY `uk_tx_hash` (`tx_hash`) USING BTREE,
+    KEY `k_account_id` (`account_id`) USING BTREE,
+    KEY `k_ct_a` (`chain_type`, `address`) USING BTREE,
+    KEY `k_tct_ta` (`to_chain_type`, `to_address`) USING BTREE,
+    KEY `k_status` (`status`) USING BTREE
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_ai_ci COMMENT ='das txs of the node tx pool';
+
+-- ----------------------------
+-- Table structure for t_provisional_change
+-- ----------------------------
+DROP TABLE IF EXISTS `t_provisional_change`;
+CREATE TABLE `t_provisional_change`
+(
+    `id`           bigint(20) unsigned                                           NOT NULL AUTO_INCREMENT COMMENT '',
+    `block_number` bigint(20) unsigned                                           NOT NULL DEFAULT '0' COMMENT '',
+    `block_hash`   varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `tx_hash`      varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `action`       varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `change_table` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '',
+    `account_id`   varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'account_id of the row if any',
+    `operation`    varchar(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci  NOT NULL DEFAULT '' COMMENT 'upsert or delete',
+    `row_data`     mediumtext CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci   COMMENT 'row after an upsert, before a delete, json',
+    `created_at`   timestamp                                                     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '',
+    PRIMARY KEY (`id`) USING BTREE,
+    KEY `k_ct_ai` (`change_table`, `account_id`) USING BTREE
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_ai_ci COMMENT ='rows written by the unconfirmed blocks';
@@ -0,0 +1,127 @@
+package das_env
+
+import (
+	"context"
+	"github.com/dotbitHQ/das-lib/common"
+	"github.com/dotbitHQ/das-lib/core"
+	"github.com/scorpiotzh/mylog"
+	"sync"
+	"time"
+)
+
+var log = mylog.NewLogger("das_env", mylog.LevelDebug)
+
+// Env keeps the das contracts, config cells and so scripts of the DasCore of a network.
+// das-lib holds them in package-level maps read by core.GetDasContractInfo and the like,
+// so the networks of one process take turns: Run swaps the ones of its network in under a process-wide lock.
+// A nil Env runs everything directly, for a process with a single network.
+type Env struct {
+	Name string
+
+	contracts        map[interface{}]interface{}
+	contractByTypeId map[string]common.DasContractName
+	configCells      map[interface{}]interface{}
+	configCellTxs    map[interface{}]interface{}
+	soScripts        map[interface{}]interface{}
+}
+
+var (
+	lock    sync.Mutex
+	current *Env
+)
+
+func New(name string) *Env {
+	return &Env{
+		Name:             name,
+		contractByTypeId: make(map[string]common.DasContractName),
+	}
+}
+
+// Run calls fn with the das-lib maps of the network, fn must not call Run again
+func (e *Env) Run(fn func() error) error {
+	if e == nil {
+		return fn()
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if current != e {
+		if current != nil {
+			current.save()
+		}
+		e.load()
+		current = e
+	}
+	return fn()
+}
+
+type dasMap interface {
+	Range(f func(key, value interface{}) bool)
+	Store(key, value interface{})
+	Delete(key interface{})
+}
+
+func (e *Env) save() {
+	e.contracts = drain(&core.DasContractMap)
+	e.configCells = drain(&core.DasConfigCellMap)
+	e.configCellTxs = drain(&core.DasConfigCellByTxHashMap)
+	e.soScripts = drain(&core.DasSoScriptMap)
+	e.contractByTypeId = core.DasContractByTypeIdMap
+}
+
+func (e *Env) load() {
+	fill(&core.DasContractMap, e.contracts)
+	fill(&core.DasConfigCellMap, e.configCells)
+	fill(&core.DasConfigCellByTxHashMap, e.configCellTxs)
+	fill(&core.DasSoScriptMap, e.soScripts)
+	core.DasContractByTypeIdMap = e.contractByTypeId
+}
+
+func drain(m dasMap) map[interface{}]interface{} {
+	saved := make(map[interface{}]interface{})
+	m.Range(func(key, value interface{}) bool {
+		saved[key] = value
+		m.Delete(key)
+		return true
+	})
+	return saved
+}
+
+func fill(m dasMap, saved map[interface{}]interface{}) {
+	for k, v := range saved {
+		m.Store(k, v)
+	}
+}
+
+// RunAsyncDasCore refreshes the outpoints of the config cells and so scripts of dc in the env,
+// like dc.RunAsyncDasConfigCell and RunAsyncDasSoScript which know nothing about it.
+// The outpoints of the contracts are not refreshed: the parser only uses their type ids, fixed by the args of the network.
+func (e *Env) RunAsyncDasCore(ctx context.Context, wg *sync.WaitGroup, dc *core.DasCore) {
+	if e == nil {
+		dc.RunAsyncDasContract(time.Minute * 5)   // contract outpoint
+		dc.RunAsyncDasConfigCell(time.Minute * 3) // config cell outpoint
+		dc.RunAsyncDasSoScript(time.Minute * 7)   // so
+		return
+	}
+	tickerConfigCell := time.NewTicker(time.Minute * 3)
+	tickerSoScript := time.NewTicker(time.Minute * 7)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			var err error
+			select {
+			case <-tickerConfigCell.C:
+				err = e.Run(dc.AsyncDasConfigCell)
+			case <-tickerSoScript.C:
+				err = e.Run(dc.InitDasSoScript)
+			case <-ctx.Done():
+				tickerConfigCell.Stop()
+				tickerSoScript.Stop()
+				return
+			}
+			if err != nil {
+				log.Error("RunAsyncDasCore err:", e.Name, err.Error())
+			}
+		}
+	}()
+}
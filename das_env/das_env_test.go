@@ -0,0 +1,44 @@
+package das_env
+
+import (
+	"github.com/dotbitHQ/das-lib/common"
+	"github.com/dotbitHQ/das-lib/core"
+	"testing"
+)
+
+func TestEnvRun(t *testing.T) {
+	mainnet, testnet := New("mainnet"), New("testnet2")
+	store := func(e *Env, typeId string) {
+		_ = e.Run(func() error {
+			core.DasContractMap.Store(common.DasContractNameAccountCellType, &core.DasContractInfo{ContractName: common.DasContractName(typeId)})
+			core.DasContractByTypeIdMap[typeId] = common.DasContractNameAccountCellType
+			return nil
+		})
+	}
+	check := func(e *Env, typeId string) {
+		_ = e.Run(func() error {
+			contract, err := core.GetDasContractInfo(common.DasContractNameAccountCellType)
+			if err != nil {
+				t.Fatal(e.Name, err)
+			} else if string(contract.ContractName) != typeId {
+				t.Fatal(e.Name, contract.ContractName, typeId)
+			}
+			if len(core.DasContractByTypeIdMap) != 1 {
+				t.Fatal(e.Name, core.DasContractByTypeIdMap)
+			} else if _, ok := core.DasContractByTypeIdMap[typeId]; !ok {
+				t.Fatal(e.Name, core.DasContractByTypeIdMap)
+			}
+			return nil
+		})
+	}
+	store(mainnet, "0x01")
+	store(testnet, "0x02")
+	check(mainnet, "0x01")
+	check(testnet, "0x02")
+	check(mainnet, "0x01")
+
+	var nilEnv *Env
+	if err := nilEnv.Run(func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+}
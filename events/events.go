@@ -0,0 +1,72 @@
+package events
+
+import (
+	"context"
+	"das_database/config"
+	"das_database/dao"
+	"encoding/json"
+	"fmt"
+	"github.com/scorpiotzh/mylog"
+)
+
+var log = mylog.NewLogger("events", mylog.LevelDebug)
+
+const (
+	PublisherNats  = "nats"
+	PublisherKafka = "kafka"
+	PublisherRedis = "redis"
+)
+
+// Event is a domain event as published, an EventReverted cancels the event RevertOf of a rolled back block
+type Event struct {
+	Id             uint64          `json:"id"`
+	Type           string          `json:"type"`
+	BlockNumber    uint64          `json:"block_number"`
+	BlockTimestamp uint64          `json:"block_timestamp"`
+	TxHash         string          `json:"tx_hash"`
+	Action         string          `json:"action"`
+	AccountId      string          `json:"account_id"`
+	Account        string          `json:"account"`
+	RevertOf       uint64          `json:"revert_of,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+func eventFromOutbox(v dao.TableEventOutbox) Event {
+	payload := json.RawMessage(v.Payload)
+	if len(payload) == 0 {
+		payload = json.RawMessage("{}")
+	}
+	return Event{
+		Id:             v.Id,
+		Type:           v.EventType,
+		BlockNumber:    v.BlockNumber,
+		BlockTimestamp: v.BlockTimestamp,
+		TxHash:         v.TxHash,
+		Action:         v.Action,
+		AccountId:      v.AccountId,
+		Account:        v.Account,
+		RevertOf:       v.RevertOf,
+		Payload:        payload,
+	}
+}
+
+// Publisher sends the events to a message broker in order, the events of a failed call are sent again
+type Publisher interface {
+	Publish(ctx context.Context, list []Event) error
+	Close() error
+}
+
+func NewPublisher(cfg config.Events) (Publisher, error) {
+	if cfg.Topic == "" {
+		return nil, fmt.Errorf("events topic is empty")
+	}
+	switch cfg.Publisher {
+	case PublisherNats:
+		return newNatsPublisher(cfg.Url, cfg.Topic)
+	case PublisherKafka:
+		return newKafkaPublisher(cfg.Url, cfg.Topic), nil
+	case PublisherRedis:
+		return newRedisPublisher(cfg.Url, cfg.Topic)
+	}
+	return nil, fmt.Errorf("unknown events publisher: %s", cfg.Publisher)
+}
@@ -0,0 +1,49 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"strings"
+)
+
+// kafkaPublisher publishes the events to the topic keyed by account id,
+// so that the events of an account stay in order within its partition
+type kafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+func newKafkaPublisher(brokers, topic string) *kafkaPublisher {
+	return &kafkaPublisher{writer: &kafka.Writer{
+		Addr:         kafka.TCP(strings.Split(brokers, ",")...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}}
+}
+
+func (k *kafkaPublisher) Publish(ctx context.Context, list []Event) error {
+	msgs := make([]kafka.Message, 0, len(list))
+	for _, v := range list {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("json.Marshal err: %s", err.Error())
+		}
+		msgs = append(msgs, kafka.Message{
+			Key:   []byte(v.AccountId),
+			Value: data,
+			Headers: []kafka.Header{
+				{Key: "type", Value: []byte(v.Type)},
+			},
+		})
+	}
+	if err := k.writer.WriteMessages(ctx, msgs...); err != nil {
+		return fmt.Errorf("kafka WriteMessages err: %s", err.Error())
+	}
+	return nil
+}
+
+func (k *kafkaPublisher) Close() error {
+	return k.writer.Close()
+}
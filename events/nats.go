@@ -0,0 +1,43 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/nats-io/nats.go"
+)
+
+// natsPublisher publishes an event to the subject <topic>.<type>
+type natsPublisher struct {
+	conn  *nats.Conn
+	topic string
+}
+
+func newNatsPublisher(url, topic string) (*natsPublisher, error) {
+	conn, err := nats.Connect(url)
+	if err != nil {
+		return nil, fmt.Errorf("nats.Connect err: %s", err.Error())
+	}
+	return &natsPublisher{conn: conn, topic: topic}, nil
+}
+
+func (n *natsPublisher) Publish(ctx context.Context, list []Event) error {
+	for _, v := range list {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("json.Marshal err: %s", err.Error())
+		}
+		if err = n.conn.Publish(n.topic+"."+v.Type, data); err != nil {
+			return fmt.Errorf("nats Publish err: %s", err.Error())
+		}
+	}
+	if err := n.conn.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("nats Flush err: %s", err.Error())
+	}
+	return nil
+}
+
+func (n *natsPublisher) Close() error {
+	n.conn.Close()
+	return nil
+}
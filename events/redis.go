@@ -0,0 +1,44 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+)
+
+// redisPublisher appends the events to the stream, the event is in the field "event"
+type redisPublisher struct {
+	client *redis.Client
+	stream string
+}
+
+func newRedisPublisher(url, stream string) (*redisPublisher, error) {
+	opt, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("redis.ParseURL err: %s", err.Error())
+	}
+	return &redisPublisher{client: redis.NewClient(opt), stream: stream}, nil
+}
+
+func (r *redisPublisher) Publish(ctx context.Context, list []Event) error {
+	pipe := r.client.TxPipeline()
+	for _, v := range list {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("json.Marshal err: %s", err.Error())
+		}
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: r.stream,
+			Values: []interface{}{"type", v.Type, "event", string(data)},
+		})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis XAdd err: %s", err.Error())
+	}
+	return nil
+}
+
+func (r *redisPublisher) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,80 @@
+package events
+
+import (
+	"context"
+	"das_database/dao"
+	"sync"
+	"time"
+)
+
+const (
+	defaultInterval  = time.Second
+	defaultBatchSize = 100
+)
+
+// Relay publishes the events of t_event_outbox once their block is committed.
+// An event is marked published after the broker acknowledged it, so it may be published twice but never lost.
+type Relay struct {
+	DbDao     *dao.DbDao
+	Publisher Publisher
+	Interval  time.Duration
+	BatchSize int
+	Ctx       context.Context
+	Wg        *sync.WaitGroup
+}
+
+func (r *Relay) Run() {
+	if r.Interval <= 0 {
+		r.Interval = defaultInterval
+	}
+	if r.BatchSize <= 0 {
+		r.BatchSize = defaultBatchSize
+	}
+	ticker := time.NewTicker(r.Interval)
+
+	r.Wg.Add(1)
+	go func() {
+		defer r.Wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				for r.publishBatch() {
+				}
+			case <-r.Ctx.Done():
+				if err := r.Publisher.Close(); err != nil {
+					log.Error("Publisher Close err:", err.Error())
+				}
+				return
+			}
+		}
+	}()
+}
+
+// publishBatch returns true if a full batch was published, more events may be pending
+func (r *Relay) publishBatch() bool {
+	list, err := r.DbDao.FindPendingEventList(r.BatchSize)
+	if err != nil {
+		log.Error("FindPendingEventList err:", err.Error())
+		return false
+	} else if len(list) == 0 {
+		return false
+	}
+
+	eventList := make([]Event, 0, len(list))
+	ids := make([]uint64, 0, len(list))
+	for _, v := range list {
+		eventList = append(eventList, eventFromOutbox(v))
+		ids = append(ids, v.Id)
+	}
+	if err = r.Publisher.Publish(r.Ctx, eventList); err != nil {
+		log.Error("Publish err:", err.Error())
+		return false
+	}
+	if err = r.DbDao.UpdateEventPublished(ids); err != nil {
+		log.Error("UpdateEventPublished err:", err.Error())
+		return false
+	}
+	log.Info("publishBatch:", len(list), list[len(list)-1].Id)
+	return len(list) == r.BatchSize && r.Ctx.Err() == nil
+}
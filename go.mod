@@ -7,10 +7,15 @@ require (
 	github.com/elazarl/goproxy v0.0.0-20220529153421-8ea89ba92021 // indirect
 	github.com/fsnotify/fsnotify v1.5.4
 	github.com/gin-gonic/gin v1.8.1
+	github.com/go-redis/redis/v8 v8.11.5
+	github.com/gorilla/websocket v1.4.2
+	github.com/nats-io/nats.go v1.16.0
 	github.com/nervosnetwork/ckb-sdk-go v0.101.3
 	github.com/parnurzeal/gorequest v0.2.16
+	github.com/prometheus/client_golang v1.12.2
 	github.com/scorpiotzh/mylog v1.0.10
 	github.com/scorpiotzh/toolib v1.1.3
+	github.com/segmentio/kafka-go v0.4.35
 	github.com/shopspring/decimal v1.3.1
 	github.com/urfave/cli/v2 v2.8.1
 	gorm.io/driver/mysql v1.3.4
@@ -10,6 +10,9 @@ const (
 	ApiCodeDbError        ApiCode = 10002
 	ApiCodeCacheError     ApiCode = 10003
 	ApiCodeBlockError     ApiCode = 10005
+	ApiCodeUnauthorized   ApiCode = 10006
+
+	ApiCodeAccountNotExist ApiCode = 20007
 
 	ApiCodeSystemUpgrade ApiCode = 30019
 )
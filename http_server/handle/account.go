@@ -0,0 +1,166 @@
+package handle
+
+import (
+	"das_database/dao"
+	"das_database/http_server/api_code"
+	"github.com/dotbitHQ/das-lib/common"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ReqAccountInfo struct {
+	Account   string `json:"account"`
+	AccountId string `json:"account_id"`
+}
+
+func (r ReqAccountInfo) getAccountId() string {
+	if r.AccountId != "" {
+		return r.AccountId
+	}
+	return common.Bytes2Hex(common.GetAccountIdByAccount(r.Account))
+}
+
+// includeUnconfirmed is the ?include_unconfirmed=true of the queries answering with the blocks not yet confirmed,
+// see t_provisional_change
+func includeUnconfirmed(ctx *gin.Context) bool {
+	return ctx.Query("include_unconfirmed") == "true"
+}
+
+// findAccountInfo returns the account with the provisional changes applied if unconfirmed
+func (h *HttpHandle) findAccountInfo(accountId string, unconfirmed bool) (dao.TableAccountInfo, error) {
+	accountInfo, err := h.dbDao.FindAccountInfoByAccountId(accountId)
+	if err != nil || !unconfirmed {
+		return accountInfo, err
+	}
+	var list []dao.TableAccountInfo
+	if accountInfo.Id > 0 {
+		list = append(list, accountInfo)
+	}
+	if err = h.dbDao.ApplyProvisionalChanges(dao.TableNameAccountInfo, accountId, &list); err != nil {
+		return accountInfo, err
+	}
+	if len(list) == 0 {
+		return dao.TableAccountInfo{}, nil
+	}
+	return list[0], nil
+}
+
+type RespAccountInfo struct {
+	AccountInfo dao.TableAccountInfo `json:"account_info"`
+}
+
+func (h *HttpHandle) AccountInfo(ctx *gin.Context) {
+	var req ReqAccountInfo
+	if err := ctx.ShouldBindJSON(&req); err != nil || (req.Account == "" && req.AccountId == "") {
+		log.Error("ShouldBindJSON err:", err)
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "params invalid"))
+		return
+	}
+	log.Info("AccountInfo", req.Account, req.AccountId, GetClientIp(ctx))
+
+	accountInfo, err := h.findAccountInfo(req.getAccountId(), includeUnconfirmed(ctx))
+	if err != nil {
+		log.Error("FindAccountInfoByAccountId err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search account err"))
+		return
+	} else if accountInfo.Id == 0 {
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeAccountNotExist, "account not exist"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, api_code.ApiRespOKData(RespAccountInfo{AccountInfo: accountInfo}))
+}
+
+type RespAccountRecords struct {
+	Account string                 `json:"account"`
+	Records []dao.TableRecordsInfo `json:"records"`
+}
+
+func (h *HttpHandle) AccountRecords(ctx *gin.Context) {
+	var req ReqAccountInfo
+	if err := ctx.ShouldBindJSON(&req); err != nil || (req.Account == "" && req.AccountId == "") {
+		log.Error("ShouldBindJSON err:", err)
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "params invalid"))
+		return
+	}
+	log.Info("AccountRecords", req.Account, req.AccountId, GetClientIp(ctx))
+
+	accountInfo, err := h.findAccountInfo(req.getAccountId(), includeUnconfirmed(ctx))
+	if err != nil {
+		log.Error("FindAccountInfoByAccountId err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search account err"))
+		return
+	} else if accountInfo.Id == 0 {
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeAccountNotExist, "account not exist"))
+		return
+	}
+	list, err := h.dbDao.FindRecordsByAccountId(accountInfo.AccountId)
+	if err == nil && includeUnconfirmed(ctx) {
+		err = h.dbDao.ApplyProvisionalChanges(dao.TableNameRecordsInfo, accountInfo.AccountId, &list)
+	}
+	if err != nil {
+		log.Error("FindRecordsByAccountId err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search records err"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, api_code.ApiRespOKData(RespAccountRecords{
+		Account: accountInfo.Account,
+		Records: list,
+	}))
+}
+
+const (
+	RoleOwner   = "owner"
+	RoleManager = "manager"
+)
+
+type ReqAccountList struct {
+	ChainType common.ChainType `json:"chain_type"`
+	Address   string           `json:"address"`
+	Role      string           `json:"role"` // owner (default) or manager
+	Pagination
+}
+
+type RespAccountList struct {
+	Total int64                  `json:"total"`
+	List  []dao.TableAccountInfo `json:"list"`
+}
+
+func (h *HttpHandle) AccountList(ctx *gin.Context) {
+	var req ReqAccountList
+	if err := ctx.ShouldBindJSON(&req); err != nil || req.Address == "" {
+		log.Error("ShouldBindJSON err:", err)
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "params invalid"))
+		return
+	}
+	log.Info("AccountList", req.ChainType, req.Address, req.Role, GetClientIp(ctx))
+
+	address, err := formatAddressHex(req.ChainType, req.Address)
+	if err != nil {
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "address invalid"))
+		return
+	}
+
+	var resp RespAccountList
+	switch req.Role {
+	case "", RoleOwner:
+		if resp.Total, err = h.dbDao.GetAccountCountByOwner(req.ChainType, address); err == nil {
+			resp.List, err = h.dbDao.FindAccountListByOwner(req.ChainType, address, req.GetLimit(), req.GetOffset())
+		}
+	case RoleManager:
+		if resp.Total, err = h.dbDao.GetAccountCountByManager(req.ChainType, address); err == nil {
+			resp.List, err = h.dbDao.FindAccountListByManager(req.ChainType, address, req.GetLimit(), req.GetOffset())
+		}
+	default:
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "role invalid"))
+		return
+	}
+	if err != nil {
+		log.Error("search account list err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search account list err"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, api_code.ApiRespOKData(resp))
+}
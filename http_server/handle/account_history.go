@@ -0,0 +1,80 @@
+package handle
+
+import (
+	"das_database/dao"
+	"das_database/http_server/api_code"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ReqAccountHistory struct {
+	ReqAccountInfo
+	Pagination
+}
+
+type RespAccountHistory struct {
+	Total int64                     `json:"total"`
+	List  []dao.TableAccountHistory `json:"list"`
+}
+
+func (h *HttpHandle) AccountHistory(ctx *gin.Context) {
+	var req ReqAccountHistory
+	if err := ctx.ShouldBindJSON(&req); err != nil || (req.Account == "" && req.AccountId == "") {
+		log.Error("ShouldBindJSON err:", err)
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "params invalid"))
+		return
+	}
+	log.Info("AccountHistory", req.Account, req.AccountId, GetClientIp(ctx))
+
+	accountId := req.getAccountId()
+	var resp RespAccountHistory
+	var err error
+	if resp.Total, err = h.dbDao.GetAccountHistoryCount(accountId); err == nil {
+		resp.List, err = h.dbDao.FindAccountHistory(accountId, req.GetLimit(), req.GetOffset())
+	}
+	if err != nil {
+		log.Error("search account history err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search account history err"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, api_code.ApiRespOKData(resp))
+}
+
+type ReqAccountSnapshot struct {
+	ReqAccountInfo
+	BlockNumber uint64 `json:"block_number"`
+	Timestamp   uint64 `json:"timestamp"` // block timestamp in milliseconds, used when block_number is 0
+}
+
+type RespAccountSnapshot struct {
+	AccountHistory dao.TableAccountHistory `json:"account_history"`
+}
+
+func (h *HttpHandle) AccountSnapshot(ctx *gin.Context) {
+	var req ReqAccountSnapshot
+	if err := ctx.ShouldBindJSON(&req); err != nil || (req.Account == "" && req.AccountId == "") || (req.BlockNumber == 0 && req.Timestamp == 0) {
+		log.Error("ShouldBindJSON err:", err)
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "params invalid"))
+		return
+	}
+	log.Info("AccountSnapshot", req.Account, req.AccountId, req.BlockNumber, req.Timestamp, GetClientIp(ctx))
+
+	var history dao.TableAccountHistory
+	var err error
+	if req.BlockNumber > 0 {
+		history, err = h.dbDao.FindAccountHistoryAtBlock(req.getAccountId(), req.BlockNumber)
+	} else {
+		history, err = h.dbDao.FindAccountHistoryAtTimestamp(req.getAccountId(), req.Timestamp)
+	}
+	if err != nil {
+		log.Error("search account history err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search account history err"))
+		return
+	} else if history.Id == 0 || history.Operation == dao.AccountOperationDelete {
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeAccountNotExist, "account not exist"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, api_code.ApiRespOKData(RespAccountSnapshot{AccountHistory: history}))
+}
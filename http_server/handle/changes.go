@@ -0,0 +1,77 @@
+package handle
+
+import (
+	"das_database/dao"
+	"das_database/http_server/api_code"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+const (
+	defaultChangeLimit = 100
+	maxChangeLimit     = 1000
+)
+
+type ReqChanges struct {
+	SinceBlock uint64 `form:"since_block"`
+	Cursor     uint64 `form:"cursor"` // next_cursor of the previous response, takes precedence over since_block
+	Limit      int    `form:"limit"`
+}
+
+type RespChanges struct {
+	List       []ChangeItem `json:"list"`
+	NextCursor uint64       `json:"next_cursor"`
+}
+
+type ChangeItem struct {
+	Id          uint64          `json:"id"`
+	BlockNumber uint64          `json:"block_number"`
+	TxHash      string          `json:"tx_hash"`
+	Action      string          `json:"action"`
+	Table       string          `json:"table"`
+	Key         json.RawMessage `json:"key"`
+	Operation   string          `json:"operation"`
+	Row         json.RawMessage `json:"row,omitempty"` // empty for the tombstones of deleted rows
+}
+
+func (h *HttpHandle) Changes(ctx *gin.Context) {
+	var req ReqChanges
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		log.Error("ShouldBindQuery err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "params invalid"))
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultChangeLimit
+	} else if req.Limit > maxChangeLimit {
+		req.Limit = maxChangeLimit
+	}
+
+	list, err := h.dbDao.FindChangeList(req.Cursor, req.SinceBlock, req.Limit)
+	if err != nil {
+		log.Error("FindChangeList err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search changes err"))
+		return
+	}
+
+	resp := RespChanges{List: make([]ChangeItem, 0, len(list)), NextCursor: req.Cursor}
+	for _, v := range list {
+		item := ChangeItem{
+			Id:          v.Id,
+			BlockNumber: v.BlockNumber,
+			TxHash:      v.TxHash,
+			Action:      v.Action,
+			Table:       v.ChangeTable,
+			Key:         json.RawMessage(v.RowKey),
+			Operation:   v.Operation,
+		}
+		if v.Operation == dao.RowOperationUpsert {
+			item.Row = json.RawMessage(v.RowData)
+		}
+		resp.List = append(resp.List, item)
+		resp.NextCursor = v.Id
+	}
+
+	ctx.JSON(http.StatusOK, api_code.ApiRespOKData(resp))
+}
@@ -0,0 +1,64 @@
+package handle
+
+import (
+	"das_database/dao"
+	"das_database/http_server/api_code"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ReqCrossChainLockedList struct {
+	CoinType string `json:"coin_type"` // optional
+	Pagination
+}
+
+type RespCrossChainLockedList struct {
+	Total int64                     `json:"total"`
+	List  []dao.TableCrossChainInfo `json:"list"`
+}
+
+func (h *HttpHandle) CrossChainLockedList(ctx *gin.Context) {
+	var req ReqCrossChainLockedList
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Error("ShouldBindJSON err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "params invalid"))
+		return
+	}
+	log.Info("CrossChainLockedList", req.CoinType, GetClientIp(ctx))
+
+	var resp RespCrossChainLockedList
+	var err error
+	if resp.Total, err = h.dbDao.GetCrossChainLockedCount(req.CoinType); err == nil {
+		resp.List, err = h.dbDao.FindCrossChainLockedList(req.CoinType, req.GetLimit(), req.GetOffset())
+	}
+	if err != nil {
+		log.Error("search cross chain list err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search cross chain list err"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, api_code.ApiRespOKData(resp))
+}
+
+type RespCrossChainHistory struct {
+	List []dao.TableCrossChainInfo `json:"list"`
+}
+
+func (h *HttpHandle) CrossChainHistory(ctx *gin.Context) {
+	var req ReqAccountInfo
+	if err := ctx.ShouldBindJSON(&req); err != nil || (req.Account == "" && req.AccountId == "") {
+		log.Error("ShouldBindJSON err:", err)
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "params invalid"))
+		return
+	}
+	log.Info("CrossChainHistory", req.Account, req.AccountId, GetClientIp(ctx))
+
+	list, err := h.dbDao.FindCrossChainListByAccountId(req.getAccountId())
+	if err != nil {
+		log.Error("FindCrossChainListByAccountId err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search cross chain history err"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, api_code.ApiRespOKData(RespCrossChainHistory{List: list}))
+}
@@ -0,0 +1,78 @@
+package handle
+
+import (
+	"das_database/dao"
+	"das_database/http_server/api_code"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ReqDeadLetterList struct {
+	Status int `json:"status"` // 0: quarantined 1: released 2: processed
+	Pagination
+}
+
+type RespDeadLetterList struct {
+	Total int64                     `json:"total"`
+	List  []dao.TableDeadLetterInfo `json:"list"`
+}
+
+func (h *HttpHandle) DeadLetterList(ctx *gin.Context) {
+	var req ReqDeadLetterList
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Error("ShouldBindJSON err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "params invalid"))
+		return
+	}
+	log.Info("DeadLetterList", req.Status, GetClientIp(ctx))
+
+	var resp RespDeadLetterList
+	var err error
+	if resp.Total, err = h.dbDao.GetDeadLetterCount(req.Status); err != nil {
+		log.Error("GetDeadLetterCount err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search dead letter err"))
+		return
+	}
+	if resp.List, err = h.dbDao.FindDeadLetterList(req.Status, req.GetLimit(), req.GetOffset()); err != nil {
+		log.Error("FindDeadLetterList err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search dead letter err"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, api_code.ApiRespOKData(resp))
+}
+
+type ReqDeadLetterRelease struct {
+	Ids []uint64 `json:"ids"`
+}
+
+type RespDeadLetterRelease struct {
+	Released int64 `json:"released"`
+}
+
+// DeadLetterRelease hands the quarantined txs back to the parser, it re-processes them once caught up with the tip
+func (h *HttpHandle) DeadLetterRelease(ctx *gin.Context) {
+	var req ReqDeadLetterRelease
+	if err := ctx.ShouldBindJSON(&req); err != nil || len(req.Ids) == 0 {
+		log.Error("ShouldBindJSON err:", err)
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "params invalid"))
+		return
+	}
+	log.Info("DeadLetterRelease", req.Ids, GetClientIp(ctx))
+
+	released, err := h.dbDao.ReleaseDeadLetter(req.Ids)
+	if err != nil {
+		log.Error("ReleaseDeadLetter err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "release dead letter err"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, api_code.ApiRespOKData(RespDeadLetterRelease{Released: released}))
+}
+
+// ParserResume lets the parser halted by the parser policy retry again
+func (h *HttpHandle) ParserResume(ctx *gin.Context) {
+	log.Info("ParserResume", GetClientIp(ctx))
+	h.bp.Resume()
+	ctx.JSON(http.StatusOK, api_code.ApiRespOKData(h.bp.State()))
+}
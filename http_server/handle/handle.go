@@ -5,11 +5,10 @@ import (
 	"das_database/block_parser"
 	"das_database/dao"
 	"das_database/http_server/api_code"
+	"errors"
 	"fmt"
 	"github.com/dotbitHQ/das-lib/core"
-	"github.com/dotbitHQ/das-lib/witness"
 	"github.com/gin-gonic/gin"
-	"github.com/nervosnetwork/ckb-sdk-go/types"
 	"github.com/scorpiotzh/mylog"
 	"net/http"
 )
@@ -19,6 +18,7 @@ var (
 )
 
 type HttpHandle struct {
+	network string
 	ctx     context.Context
 	dbDao   *dao.DbDao
 	dasCore *core.DasCore
@@ -26,6 +26,7 @@ type HttpHandle struct {
 }
 
 type HttpHandleParams struct {
+	Network string // name of the network in the config, for its health section
 	DbDao   *dao.DbDao
 	DasCore *core.DasCore
 	Ctx     context.Context
@@ -34,6 +35,7 @@ type HttpHandleParams struct {
 
 func Initialize(p HttpHandleParams) *HttpHandle {
 	hh := HttpHandle{
+		network: p.Network,
 		dbDao:   p.DbDao,
 		dasCore: p.DasCore,
 		ctx:     p.Ctx,
@@ -59,7 +61,7 @@ func (h *HttpHandle) IsLatestBlockNumber(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, api_code.ApiRespOKData(map[string]interface{}{
 		"blockNumber":         blockNumber,
-		"isLatestBlockNumber": block_parser.IsLatestBlockNumber,
+		"isLatestBlockNumber": h.bp.State().IsLatest,
 	}))
 }
 
@@ -77,41 +79,14 @@ func (h *HttpHandle) ParserTransaction(ctx *gin.Context) {
 
 	log.Info("ParserTransaction", transactionData.TxHash, GetClientIp(ctx))
 
-	tx, err := h.dasCore.Client().GetTransaction(h.ctx, types.HexToHash(transactionData.TxHash))
-	if err != nil {
-		log.Error("GetTransaction err: %s", err.Error())
-		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeBlockError, "search transaction err"))
-		return
-	}
-	header, err := h.dasCore.Client().GetHeader(h.ctx, types.HexToHash(tx.TxStatus.BlockHash.Hex()))
-	if err != nil {
-		log.Error("GetHeader err: %s", err.Error())
-		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeBlockError, "search header err"))
-		return
-	}
-
-	builder, err := witness.ActionDataBuilderFromTx(tx.Transaction)
-	if err != nil {
-		log.Error("ActionDataBuilderFromTx err: %s", err.Error())
+	if action, err := h.bp.ParseTransaction(transactionData.TxHash); errors.Is(err, block_parser.ErrNotDasTransaction) {
+		log.Error("ParseTransaction err:", transactionData.TxHash, err.Error())
 		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeBlockError, "builder from tx err"))
 		return
-	}
-
-	if handle, ok := h.bp.GetMapTransactionHandle(builder.Action); ok {
-		// 根据对应的 action 进行交易解析
-		resp := handle(block_parser.FuncTransactionHandleReq{
-			DbDao:          h.dbDao,
-			Tx:             tx.Transaction,
-			TxHash:         transactionData.TxHash,
-			BlockNumber:    header.Number,
-			BlockTimestamp: header.Timestamp,
-			Action:         builder.Action,
-		})
-		if resp.Err != nil {
-			log.Error("action handle resp:", builder.Action, header.Number, transactionData, resp.Err.Error())
-			ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeBlockError, "action handle err"))
-			return
-		}
+	} else if err != nil {
+		log.Error("ParseTransaction err:", action, transactionData.TxHash, err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeBlockError, "parser transaction err"))
+		return
 	}
 
 	ctx.JSON(http.StatusOK, api_code.ApiRespOK())
@@ -0,0 +1,96 @@
+package handle
+
+import (
+	"context"
+	"das_database/block_parser"
+	"das_database/config"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+const healthCheckTimeout = time.Second * 3
+
+type RespHealth struct {
+	Status string                   `json:"status"` // ok or fail
+	Checks map[string]string        `json:"checks"` // check name => ok or the failure reason
+	Parser block_parser.ParserState `json:"parser"`
+	Lag    uint64                   `json:"lag"`
+}
+
+func (r *RespHealth) check(name string, err error) {
+	if err != nil {
+		r.Checks[name] = err.Error()
+		r.Status = "fail"
+	} else {
+		r.Checks[name] = "ok"
+	}
+}
+
+func (r *RespHealth) write(ctx *gin.Context) {
+	code := http.StatusOK
+	if r.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+	ctx.JSON(code, r)
+}
+
+func (h *HttpHandle) checkDb(resp *RespHealth) {
+	ctx, cancel := context.WithTimeout(h.ctx, healthCheckTimeout)
+	defer cancel()
+	resp.check("db", h.dbDao.Ping(ctx))
+}
+
+func (h *HttpHandle) checkParserStuck(resp *RespHealth) {
+	maxErrRetry := config.Cfg.Policy(h.network).Health.MaxErrRetry
+	if resp.Parser.Halted {
+		resp.check("parser_stuck", fmt.Errorf("halted by the parser policy, last: %s", resp.Parser.LastErr))
+	} else if maxErrRetry > 0 && resp.Parser.ErrRetryCount >= maxErrRetry {
+		resp.check("parser_stuck", fmt.Errorf("%d consecutive errors, last: %s", resp.Parser.ErrRetryCount, resp.Parser.LastErr))
+	} else {
+		resp.check("parser_stuck", nil)
+	}
+}
+
+// Healthz is the liveness probe: the db is reachable and the parser is not stuck retrying the same error
+func (h *HttpHandle) Healthz(ctx *gin.Context) {
+	resp := RespHealth{Status: "ok", Checks: map[string]string{}, Parser: h.bp.State()}
+	h.checkDb(&resp)
+	h.checkParserStuck(&resp)
+	resp.write(ctx)
+}
+
+// Readyz is the readiness probe: healthy, the ckb node is reachable and the parser has caught up with the tip
+func (h *HttpHandle) Readyz(ctx *gin.Context) {
+	resp := RespHealth{Status: "ok", Checks: map[string]string{}, Parser: h.bp.State()}
+	h.checkDb(&resp)
+	h.checkParserStuck(&resp)
+
+	nodeCtx, cancel := context.WithTimeout(h.ctx, healthCheckTimeout)
+	defer cancel()
+	tipBlockNumber, err := h.dasCore.Client().GetTipBlockNumber(nodeCtx)
+	resp.check("ckb_node", err)
+	if err != nil {
+		tipBlockNumber = resp.Parser.TipBlockNumber
+	}
+
+	if tipBlockNumber > resp.Parser.CurrentBlockNumber {
+		resp.Lag = tipBlockNumber - resp.Parser.CurrentBlockNumber
+	}
+	cfg := config.Cfg.Policy(h.network).Health
+	if maxLag := cfg.MaxBlockLag; maxLag > 0 && resp.Lag > maxLag {
+		resp.check("parser_lag", fmt.Errorf("lag %d > %d", resp.Lag, maxLag))
+	} else {
+		resp.check("parser_lag", nil)
+	}
+
+	maxDelay := time.Duration(cfg.MaxBlockDelay) * time.Second
+	if maxDelay > 0 && !resp.Parser.IsLatest && time.Since(resp.Parser.LastBlockAt) > maxDelay {
+		resp.check("parser_delay", fmt.Errorf("no block parsed since %s", resp.Parser.LastBlockAt.Format("2006-01-02 15:04:05")))
+	} else {
+		resp.check("parser_delay", nil)
+	}
+
+	resp.write(ctx)
+}
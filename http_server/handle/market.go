@@ -0,0 +1,202 @@
+package handle
+
+import (
+	"das_database/dao"
+	"das_database/http_server/api_code"
+	"github.com/dotbitHQ/das-lib/common"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"net/http"
+)
+
+type ReqTradeList struct {
+	PriceCkbMin   uint64          `json:"price_ckb_min"`
+	PriceCkbMax   uint64          `json:"price_ckb_max"`
+	PriceUsdMin   decimal.Decimal `json:"price_usd_min"`
+	PriceUsdMax   decimal.Decimal `json:"price_usd_max"`
+	CharsetNum    uint64          `json:"charset_num"`
+	AccountLenMin int             `json:"account_len_min"`
+	AccountLenMax int             `json:"account_len_max"`
+	SortBy        string          `json:"sort_by"` // started_at (default) or price
+	Desc          bool            `json:"desc"`
+	Pagination
+}
+
+type RespTradeList struct {
+	Total int64                `json:"total"`
+	List  []dao.TableTradeInfo `json:"list"`
+}
+
+func (h *HttpHandle) TradeList(ctx *gin.Context) {
+	var req ReqTradeList
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Error("ShouldBindJSON err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "params invalid"))
+		return
+	}
+	if req.SortBy != "" && req.SortBy != dao.TradeSortByStartedAt && req.SortBy != dao.TradeSortByPrice {
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "sort_by invalid"))
+		return
+	}
+	log.Info("TradeList", req.SortBy, req.Desc, req.Page, req.Size, GetClientIp(ctx))
+
+	filter := dao.TradeListFilter{
+		PriceCkbMin:   req.PriceCkbMin,
+		PriceCkbMax:   req.PriceCkbMax,
+		PriceUsdMin:   req.PriceUsdMin,
+		PriceUsdMax:   req.PriceUsdMax,
+		CharsetNum:    req.CharsetNum,
+		AccountLenMin: req.AccountLenMin,
+		AccountLenMax: req.AccountLenMax,
+		SortBy:        req.SortBy,
+		Desc:          req.Desc,
+	}
+	var resp RespTradeList
+	var err error
+	if resp.Total, err = h.dbDao.GetTradeCount(filter); err != nil {
+		log.Error("GetTradeCount err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search trade list err"))
+		return
+	}
+	if resp.List, err = h.dbDao.FindTradeList(filter, req.GetLimit(), req.GetOffset()); err != nil {
+		log.Error("FindTradeList err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search trade list err"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, api_code.ApiRespOKData(resp))
+}
+
+type ReqOfferList struct {
+	Account   string           `json:"account"`
+	ChainType common.ChainType `json:"chain_type"`
+	Address   string           `json:"address"`
+	Pagination
+}
+
+type RespOfferList struct {
+	Total int64                `json:"total"`
+	List  []dao.TableOfferInfo `json:"list"`
+}
+
+// OfferList returns the offers received by the account, or made by the address
+func (h *HttpHandle) OfferList(ctx *gin.Context) {
+	var req ReqOfferList
+	if err := ctx.ShouldBindJSON(&req); err != nil || (req.Account == "" && req.Address == "") {
+		log.Error("ShouldBindJSON err:", err)
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "params invalid"))
+		return
+	}
+	log.Info("OfferList", req.Account, req.ChainType, req.Address, GetClientIp(ctx))
+
+	var resp RespOfferList
+	var err error
+	if req.Account != "" {
+		accountId := common.Bytes2Hex(common.GetAccountIdByAccount(req.Account))
+		if resp.Total, err = h.dbDao.GetOfferCountByAccountId(accountId); err == nil {
+			resp.List, err = h.dbDao.FindOfferListByAccountId(accountId, req.GetLimit(), req.GetOffset())
+		}
+	} else {
+		address, e := formatAddressHex(req.ChainType, req.Address)
+		if e != nil {
+			ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "address invalid"))
+			return
+		}
+		if resp.Total, err = h.dbDao.GetOfferCountByAddress(req.ChainType, address); err == nil {
+			resp.List, err = h.dbDao.FindOfferListByAddress(req.ChainType, address, req.GetLimit(), req.GetOffset())
+		}
+	}
+	if err != nil {
+		log.Error("search offer list err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search offer list err"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, api_code.ApiRespOKData(resp))
+}
+
+type ReqDealList struct {
+	Account string `json:"account"`
+	Pagination
+}
+
+type RespDealList struct {
+	List []dao.TableTradeDealInfo `json:"list"`
+}
+
+// DealList returns the latest deals, optionally of one account
+func (h *HttpHandle) DealList(ctx *gin.Context) {
+	var req ReqDealList
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Error("ShouldBindJSON err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "params invalid"))
+		return
+	}
+	log.Info("DealList", req.Account, GetClientIp(ctx))
+
+	var resp RespDealList
+	var err error
+	if req.Account != "" {
+		accountId := common.Bytes2Hex(common.GetAccountIdByAccount(req.Account))
+		resp.List, err = h.dbDao.FindDealListByAccountId(accountId, req.GetLimit(), req.GetOffset())
+	} else {
+		resp.List, err = h.dbDao.FindRecentDealList(req.GetLimit(), req.GetOffset())
+	}
+	if err != nil {
+		log.Error("search deal list err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search deal list err"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, api_code.ApiRespOKData(resp))
+}
+
+type ReqRebateList struct {
+	InviterId string           `json:"inviter_id"`
+	ChainType common.ChainType `json:"chain_type"`
+	Address   string           `json:"address"`
+	Pagination
+}
+
+type RespRebateList struct {
+	Total  int64                 `json:"total"`
+	Reward uint64                `json:"reward"`
+	List   []dao.TableRebateInfo `json:"list"`
+}
+
+// RebateList returns the rebates earned by the inviter account id, or by the inviter address
+func (h *HttpHandle) RebateList(ctx *gin.Context) {
+	var req ReqRebateList
+	if err := ctx.ShouldBindJSON(&req); err != nil || (req.InviterId == "" && req.Address == "") {
+		log.Error("ShouldBindJSON err:", err)
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "params invalid"))
+		return
+	}
+	log.Info("RebateList", req.InviterId, req.ChainType, req.Address, GetClientIp(ctx))
+
+	var sum dao.RebateSum
+	var resp RespRebateList
+	var err error
+	if req.InviterId != "" {
+		if sum, err = h.dbDao.GetRebateSumByInviterId(req.InviterId); err == nil {
+			resp.List, err = h.dbDao.FindRebateListByInviterId(req.InviterId, req.GetLimit(), req.GetOffset())
+		}
+	} else {
+		address, e := formatAddressHex(req.ChainType, req.Address)
+		if e != nil {
+			ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "address invalid"))
+			return
+		}
+		if sum, err = h.dbDao.GetRebateSumByInviterAddress(req.ChainType, address); err == nil {
+			resp.List, err = h.dbDao.FindRebateListByInviterAddress(req.ChainType, address, req.GetLimit(), req.GetOffset())
+		}
+	}
+	if err != nil {
+		log.Error("search rebate list err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search rebate list err"))
+		return
+	}
+	resp.Total, resp.Reward = sum.Total, sum.Reward
+
+	ctx.JSON(http.StatusOK, api_code.ApiRespOKData(resp))
+}
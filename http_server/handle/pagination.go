@@ -0,0 +1,45 @@
+package handle
+
+import (
+	"github.com/dotbitHQ/das-lib/common"
+	"strings"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type Pagination struct {
+	Page int `json:"page"`
+	Size int `json:"size"`
+}
+
+func (p Pagination) GetLimit() int {
+	if p.Size <= 0 {
+		return defaultPageSize
+	} else if p.Size > maxPageSize {
+		return maxPageSize
+	}
+	return p.Size
+}
+
+func (p Pagination) GetOffset() int {
+	if p.Page <= 1 {
+		return 0
+	}
+	return (p.Page - 1) * p.GetLimit()
+}
+
+// formatAddressHex converts the address to the form stored in db
+func formatAddressHex(chainType common.ChainType, address string) (string, error) {
+	switch chainType {
+	case common.ChainTypeEth:
+		return strings.ToLower(address), nil
+	case common.ChainTypeTron:
+		if strings.HasPrefix(address, common.TronBase58PreFix) {
+			return common.TronBase58ToHex(address)
+		}
+	}
+	return address, nil
+}
@@ -0,0 +1,53 @@
+package handle
+
+import (
+	"das_database/dao"
+	"das_database/http_server/api_code"
+	"github.com/dotbitHQ/das-lib/common"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ReqPendingTxList struct {
+	Account   string           `json:"account"`
+	ChainType common.ChainType `json:"chain_type"`
+	Address   string           `json:"address"`
+	Status    []int            `json:"status"` // 0 pending 1 committed 2 rejected, all by default
+	Pagination
+}
+
+type RespPendingTxList struct {
+	List []dao.TablePendingTx `json:"list"`
+}
+
+// PendingTxList returns the das txs of the account or address seen in the tx pool, newest first
+func (h *HttpHandle) PendingTxList(ctx *gin.Context) {
+	var req ReqPendingTxList
+	if err := ctx.ShouldBindJSON(&req); err != nil || (req.Account == "" && req.Address == "") {
+		log.Error("ShouldBindJSON err:", err)
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "params invalid"))
+		return
+	}
+	log.Info("PendingTxList", req.Account, req.ChainType, req.Address, req.Status, GetClientIp(ctx))
+
+	filter := dao.PendingTxFilter{Status: req.Status}
+	if req.Account != "" {
+		filter.AccountId = common.Bytes2Hex(common.GetAccountIdByAccount(req.Account))
+	} else {
+		address, err := formatAddressHex(req.ChainType, req.Address)
+		if err != nil {
+			ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "address invalid"))
+			return
+		}
+		filter.ChainType, filter.Address = req.ChainType, address
+	}
+
+	list, err := h.dbDao.FindPendingTxList(filter, req.GetLimit(), req.GetOffset())
+	if err != nil {
+		log.Error("FindPendingTxList err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search pending tx list err"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, api_code.ApiRespOKData(RespPendingTxList{List: list}))
+}
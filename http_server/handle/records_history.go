@@ -0,0 +1,82 @@
+package handle
+
+import (
+	"das_database/dao"
+	"das_database/http_server/api_code"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ReqRecordsHistory struct {
+	ReqAccountInfo
+	Key string `json:"key"` // optional, only the changes of this record key
+	Pagination
+}
+
+type RespRecordsHistory struct {
+	Total int64         `json:"total"`
+	List  []RecordsEdit `json:"list"`
+}
+
+type RecordsEdit struct {
+	dao.RecordsEdit
+	Added   []dao.TableRecordsHistory `json:"added"`
+	Changed []dao.TableRecordsHistory `json:"changed"`
+	Removed []dao.TableRecordsHistory `json:"removed"`
+}
+
+func (h *HttpHandle) RecordsHistory(ctx *gin.Context) {
+	var req ReqRecordsHistory
+	if err := ctx.ShouldBindJSON(&req); err != nil || (req.Account == "" && req.AccountId == "") {
+		log.Error("ShouldBindJSON err:", err)
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "params invalid"))
+		return
+	}
+	log.Info("RecordsHistory", req.Account, req.AccountId, req.Key, GetClientIp(ctx))
+
+	accountId := req.getAccountId()
+	var resp RespRecordsHistory
+	var edits []dao.RecordsEdit
+	var err error
+	if resp.Total, err = h.dbDao.GetRecordsEditCount(accountId, req.Key); err == nil {
+		edits, err = h.dbDao.FindRecordsEditList(accountId, req.Key, req.GetLimit(), req.GetOffset())
+	}
+	if err != nil {
+		log.Error("search records edit list err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search records history err"))
+		return
+	}
+
+	resp.List = make([]RecordsEdit, 0, len(edits))
+	if len(edits) > 0 {
+		var blockNumbers []uint64
+		mapEdit := make(map[string]int, len(edits))
+		for i, v := range edits {
+			blockNumbers = append(blockNumbers, v.BlockNumber)
+			mapEdit[v.TxHash] = i
+			resp.List = append(resp.List, RecordsEdit{RecordsEdit: v})
+		}
+		list, err := h.dbDao.FindRecordsHistoryByBlockNumbers(accountId, req.Key, blockNumbers)
+		if err != nil {
+			log.Error("FindRecordsHistoryByBlockNumbers err:", err.Error())
+			ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search records history err"))
+			return
+		}
+		for _, v := range list {
+			i, ok := mapEdit[v.TxHash]
+			if !ok {
+				continue
+			}
+			switch v.Operation {
+			case dao.RecordsOperationAdd:
+				resp.List[i].Added = append(resp.List[i].Added, v)
+			case dao.RecordsOperationChange:
+				resp.List[i].Changed = append(resp.List[i].Changed, v)
+			case dao.RecordsOperationRemove:
+				resp.List[i].Removed = append(resp.List[i].Removed, v)
+			}
+		}
+	}
+
+	ctx.JSON(http.StatusOK, api_code.ApiRespOKData(resp))
+}
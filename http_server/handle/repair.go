@@ -0,0 +1,34 @@
+package handle
+
+import (
+	"das_database/http_server/api_code"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ReqAccountRepair struct {
+	Account  string `json:"account"`  // account name or account id
+	Outpoint string `json:"outpoint"` // a cell of the account to walk its history from, the indexed one by default
+	DryRun   *bool  `json:"dry_run"`  // true if omitted
+}
+
+// AccountRepair rebuilds the rows of an account from its cell history and returns the diff with the indexed ones
+func (h *HttpHandle) AccountRepair(ctx *gin.Context) {
+	var req ReqAccountRepair
+	if err := ctx.ShouldBindJSON(&req); err != nil || req.Account == "" {
+		log.Error("ShouldBindJSON err:", err)
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "params invalid"))
+		return
+	}
+	dryRun := req.DryRun == nil || *req.DryRun
+	log.Info("AccountRepair", req.Account, req.Outpoint, dryRun, GetClientIp(ctx))
+
+	res, err := h.bp.RepairAccount(req.Account, req.Outpoint, dryRun)
+	if err != nil {
+		log.Error("RepairAccount err:", err.Error(), req.Account)
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeError500, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, api_code.ApiRespOKData(res))
+}
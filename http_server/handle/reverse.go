@@ -0,0 +1,58 @@
+package handle
+
+import (
+	"das_database/dao"
+	"das_database/http_server/api_code"
+	"github.com/dotbitHQ/das-lib/common"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ReqReverseRecord struct {
+	ChainType common.ChainType `json:"chain_type"`
+	Address   string           `json:"address"`
+}
+
+type RespReverseRecord struct {
+	Account     string               `json:"account"`
+	ReverseInfo dao.TableReverseInfo `json:"reverse_info"`
+}
+
+func (h *HttpHandle) ReverseRecord(ctx *gin.Context) {
+	var req ReqReverseRecord
+	if err := ctx.ShouldBindJSON(&req); err != nil || req.Address == "" {
+		log.Error("ShouldBindJSON err:", err)
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "params invalid"))
+		return
+	}
+	log.Info("ReverseRecord", req.ChainType, req.Address, GetClientIp(ctx))
+
+	address, err := formatAddressHex(req.ChainType, req.Address)
+	if err != nil {
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "address invalid"))
+		return
+	}
+	reverseInfo, err := h.dbDao.FindLatestReverseRecord(req.ChainType, address)
+	if err != nil {
+		log.Error("FindLatestReverseRecord err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search reverse record err"))
+		return
+	}
+
+	// the reverse record only takes effect while the account still exists
+	var resp RespReverseRecord
+	if reverseInfo.Id > 0 {
+		accountInfo, err := h.dbDao.FindAccountInfoByAccountId(reverseInfo.AccountId)
+		if err != nil {
+			log.Error("FindAccountInfoByAccountId err:", err.Error())
+			ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search account err"))
+			return
+		}
+		if accountInfo.Id > 0 {
+			resp.Account = accountInfo.Account
+		}
+		resp.ReverseInfo = reverseInfo
+	}
+
+	ctx.JSON(http.StatusOK, api_code.ApiRespOKData(resp))
+}
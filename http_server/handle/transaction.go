@@ -0,0 +1,92 @@
+package handle
+
+import (
+	"das_database/dao"
+	"das_database/http_server/api_code"
+	"fmt"
+	"github.com/dotbitHQ/das-lib/common"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type ReqTransactionList struct {
+	Account     string           `json:"account"`
+	ChainType   common.ChainType `json:"chain_type"`
+	Address     string           `json:"address"`
+	Actions     []string         `json:"actions"`
+	ServiceType int              `json:"service_type"`
+	Cursor      string           `json:"cursor"` // next_cursor of the previous page
+	Size        int              `json:"size"`
+}
+
+type RespTransactionList struct {
+	List       []dao.TableTransactionInfo `json:"list"`
+	NextCursor string                     `json:"next_cursor"`
+}
+
+// the cursor is "{block_number}_{id}" of the last row of the previous page
+func formatTxCursor(tx dao.TableTransactionInfo) string {
+	return fmt.Sprintf("%d_%d", tx.BlockNumber, tx.Id)
+}
+
+func parseTxCursor(cursor string) (blockNumber, id uint64, err error) {
+	parts := strings.Split(cursor, "_")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("cursor invalid: %s", cursor)
+	}
+	if blockNumber, err = strconv.ParseUint(parts[0], 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("ParseUint err: %s", err.Error())
+	}
+	if id, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("ParseUint err: %s", err.Error())
+	}
+	return
+}
+
+func (h *HttpHandle) TransactionList(ctx *gin.Context) {
+	var req ReqTransactionList
+	if err := ctx.ShouldBindJSON(&req); err != nil || (req.Account == "" && req.Address == "") {
+		log.Error("ShouldBindJSON err:", err)
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "params invalid"))
+		return
+	}
+	log.Info("TransactionList", req.Account, req.ChainType, req.Address, req.Actions, req.Cursor, GetClientIp(ctx))
+
+	filter := dao.TransactionListFilter{
+		Actions:     req.Actions,
+		ServiceType: req.ServiceType,
+	}
+	if req.Account != "" {
+		filter.AccountId = common.Bytes2Hex(common.GetAccountIdByAccount(req.Account))
+	} else {
+		address, err := formatAddressHex(req.ChainType, req.Address)
+		if err != nil {
+			ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "address invalid"))
+			return
+		}
+		filter.ChainType, filter.Address = req.ChainType, address
+	}
+	if req.Cursor != "" {
+		var err error
+		if filter.CursorBlockNumber, filter.CursorId, err = parseTxCursor(req.Cursor); err != nil {
+			ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "cursor invalid"))
+			return
+		}
+	}
+
+	limit := Pagination{Size: req.Size}.GetLimit()
+	list, err := h.dbDao.FindTransactionList(filter, limit)
+	if err != nil {
+		log.Error("FindTransactionList err:", err.Error())
+		ctx.JSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeDbError, "search transaction list err"))
+		return
+	}
+
+	resp := RespTransactionList{List: list}
+	if len(list) == limit {
+		resp.NextCursor = formatTxCursor(list[len(list)-1])
+	}
+	ctx.JSON(http.StatusOK, api_code.ApiRespOKData(resp))
+}
@@ -2,13 +2,20 @@ package http_server
 
 import (
 	"context"
+	"crypto/subtle"
 	"das_database/block_parser"
+	"das_database/config"
 	"das_database/dao"
+	"das_database/http_server/api_code"
 	"das_database/http_server/handle"
+	"das_database/metrics"
 	"github.com/dotbitHQ/das-lib/core"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/scorpiotzh/mylog"
 	"net/http"
+	"strings"
+	"time"
 )
 
 var (
@@ -16,11 +23,13 @@ var (
 )
 
 type HttpServer struct {
-	address string
-	engine  *gin.Engine
-	h       *handle.HttpHandle
-	srv     *http.Server
-	ctx     context.Context
+	address  string
+	engine   *gin.Engine
+	h        *handle.HttpHandle
+	network  string
+	networks map[string]*handle.HttpHandle
+	srv      *http.Server
+	ctx      context.Context
 }
 
 type HttpServerParams struct {
@@ -29,6 +38,10 @@ type HttpServerParams struct {
 	Ctx     context.Context
 	DasCore *core.DasCore
 	Bp      *block_parser.BlockParser
+	// optional, the routes are also served under /v1/{Network}/...
+	Network string
+	// optional, the other networks (or deployments) parsed by this process, served under /v1/{name}/...
+	Networks map[string]handle.HttpHandleParams
 }
 
 func Initialize(p HttpServerParams) (*HttpServer, error) {
@@ -36,21 +49,46 @@ func Initialize(p HttpServerParams) (*HttpServer, error) {
 		address: p.Address,
 		engine:  gin.New(),
 		h: handle.Initialize(handle.HttpHandleParams{
+			Network: p.Network,
 			DbDao:   p.DbDao,
 			DasCore: p.DasCore,
 			Ctx:     p.Ctx,
 			Bp:      p.Bp,
 		}),
-		ctx: p.Ctx,
+		network:  p.Network,
+		networks: make(map[string]*handle.HttpHandle),
+		ctx:      p.Ctx,
+	}
+	for name, params := range p.Networks {
+		params.Network = name
+		params.Ctx = p.Ctx
+		hs.networks[name] = handle.Initialize(params)
 	}
 	return &hs, nil
 }
 
 func (h *HttpServer) Run() {
+	h.engine.Use(metricsHandler())
+	h.engine.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	h.engine.GET("/healthz", h.h.Healthz)
+	h.engine.GET("/readyz", h.h.Readyz)
+
 	v1 := h.engine.Group("v1")
-	{
-		v1.POST("/latest/block/number", h.h.IsLatestBlockNumber) // check if the newest height
-		v1.POST("/parser/transaction", h.h.ParserTransaction)
+	registerReadRoutes(v1, h.h)
+	registerNodeRoutes(v1, h.h, h.network)
+	if h.network != "" {
+		group := h.engine.Group("v1/" + h.network)
+		group.GET("/healthz", h.h.Healthz)
+		group.GET("/readyz", h.h.Readyz)
+		registerReadRoutes(group, h.h)
+		registerNodeRoutes(group, h.h, h.network)
+	}
+	for name, hh := range h.networks {
+		group := h.engine.Group("v1/" + name)
+		group.GET("/healthz", hh.Healthz)
+		group.GET("/readyz", hh.Readyz)
+		registerReadRoutes(group, hh)
+		registerNodeRoutes(group, hh, name)
 	}
 
 	h.srv = &http.Server{
@@ -63,3 +101,83 @@ func (h *HttpServer) Run() {
 		}
 	}()
 }
+
+// registerReadRoutes registers the routes answered from the db only,
+// only account/info and account/records honour ?include_unconfirmed=true
+func registerReadRoutes(group *gin.RouterGroup, hh *handle.HttpHandle) {
+	group.POST("/account/info", hh.AccountInfo)
+	group.POST("/account/records", hh.AccountRecords)
+
+	group = group.Group("", confirmedOnly())
+	group.POST("/account/records/history", hh.RecordsHistory)
+	group.POST("/account/list", hh.AccountList)
+	group.POST("/account/history", hh.AccountHistory)
+	group.POST("/account/snapshot", hh.AccountSnapshot)
+	group.POST("/reverse/record", hh.ReverseRecord)
+
+	group.POST("/trade/list", hh.TradeList)
+	group.POST("/offer/list", hh.OfferList)
+	group.POST("/deal/list", hh.DealList)
+	group.POST("/rebate/list", hh.RebateList)
+
+	group.POST("/transaction/list", hh.TransactionList)
+	group.POST("/pending/tx/list", hh.PendingTxList)
+	group.GET("/changes", hh.Changes)
+
+	group.POST("/cross/chain/locked/list", hh.CrossChainLockedList)
+	group.POST("/cross/chain/history", hh.CrossChainHistory)
+}
+
+// registerNodeRoutes registers the routes using the ckb node or the parser of the network
+func registerNodeRoutes(group *gin.RouterGroup, hh *handle.HttpHandle, network string) {
+	group.POST("/latest/block/number", hh.IsLatestBlockNumber) // check if the newest height
+	group.POST("/parser/transaction", hh.ParserTransaction)
+
+	// the admin routes are only served with an admin_token set for the network
+	if config.Cfg.Policy(network).AdminToken == "" {
+		return
+	}
+	admin := group.Group("/admin", adminAuth(network))
+	admin.POST("/dead/letter/list", hh.DeadLetterList)
+	admin.POST("/dead/letter/release", hh.DeadLetterRelease)
+	admin.POST("/parser/resume", hh.ParserResume)
+	admin.POST("/account/repair", hh.AccountRepair)
+}
+
+// adminAuth requires the header Authorization: Bearer {admin_token of the network}, read on every request so it can be rotated
+func adminAuth(network string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := config.Cfg.Policy(network).AdminToken
+		auth := ctx.GetHeader("Authorization")
+		if token == "" || !strings.HasPrefix(auth, "Bearer ") ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) != 1 {
+			log.Warn("adminAuth unauthorized:", ctx.Request.URL.Path, ctx.ClientIP())
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, api_code.ApiRespErr(api_code.ApiCodeUnauthorized, "unauthorized"))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// confirmedOnly rejects ?include_unconfirmed=true on the routes which only answer with the confirmed rows
+func confirmedOnly() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Query("include_unconfirmed") == "true" {
+			ctx.AbortWithStatusJSON(http.StatusOK, api_code.ApiRespErr(api_code.ApiCodeParamsInvalid, "include_unconfirmed not supported"))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func metricsHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+		path := ctx.FullPath()
+		if path == "" {
+			path = "unmatched" // keep the label cardinality bounded
+		}
+		metrics.ObserveHttp(ctx.Request.Method, path, ctx.Writer.Status(), start)
+	}
+}
@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const namespace = "das_database"
+
+var (
+	// parser
+	CurrentBlockNumber = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "parser",
+		Name:      "current_block_number",
+		Help:      "The next block number to be parsed.",
+	}, []string{"network"})
+	TipBlockNumber = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "parser",
+		Name:      "tip_block_number",
+		Help:      "The tip block number of the ckb node.",
+	}, []string{"network"})
+	BlockLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "parser",
+		Name:      "block_lag",
+		Help:      "Number of blocks the parser is behind the ckb node tip.",
+	}, []string{"network"})
+	BlocksTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "parser",
+		Name:      "blocks_total",
+		Help:      "Number of blocks parsed, rate() gives blocks per second.",
+	}, []string{"network"})
+	ForksTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "parser",
+		Name:      "forks_total",
+		Help:      "Number of forks detected, each rolls back one block.",
+	}, []string{"network"})
+	ActionHandleTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "parser",
+		Name:      "action_handle_total",
+		Help:      "Number of transactions handled per das action.",
+	}, []string{"network", "action"})
+	ActionHandleErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "parser",
+		Name:      "action_handle_errors_total",
+		Help:      "Number of transactions failed to be handled per das action.",
+	}, []string{"network", "action"})
+	ActionHandleDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "parser",
+		Name:      "action_handle_duration_seconds",
+		Help:      "Latency of the transaction handlers per das action.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"network", "action"})
+
+	// ckb rpc
+	RpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "rpc",
+		Name:      "duration_seconds",
+		Help:      "Latency of the ckb node rpc calls.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"network", "method", "status"})
+
+	// db
+	DbStatementDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "statement_duration_seconds",
+		Help:      "Latency of the db statements per DbDao method issuing them.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"network", "method", "status"})
+	DbTransactionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "transaction_duration_seconds",
+		Help:      "Latency of the db transactions, commit included, per method opening them.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"network", "method", "status"})
+
+	// http server
+	HttpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of http requests.",
+	}, []string{"method", "path", "code"})
+	HttpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the http requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "path"})
+
+	// timer
+	tokenPriceRefreshedAt int64
+	_                     = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "timer",
+		Name:      "token_price_refresh_age_seconds",
+		Help:      "Seconds since the token prices were refreshed successfully, -1 if never.",
+	}, func() float64 {
+		refreshedAt := atomic.LoadInt64(&tokenPriceRefreshedAt)
+		if refreshedAt == 0 {
+			return -1
+		}
+		return time.Since(time.Unix(0, refreshedAt)).Seconds()
+	})
+)
+
+func statusLabel(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// ObserveRpc records the latency of a ckb node rpc call of the network started at start
+func ObserveRpc(network, method string, start time.Time, err error) {
+	RpcDuration.WithLabelValues(network, method, statusLabel(err)).Observe(time.Since(start).Seconds())
+}
+
+// ObserveDb records the latency of a db statement issued by the DbDao method on the db of the network
+func ObserveDb(network, method string, start time.Time, err error) {
+	DbStatementDuration.WithLabelValues(network, method, statusLabel(err)).Observe(time.Since(start).Seconds())
+}
+
+// ObserveDbTransaction records the latency of a db transaction opened by the method on the db of the network
+func ObserveDbTransaction(network, method string, start time.Time, err error) {
+	DbTransactionDuration.WithLabelValues(network, method, statusLabel(err)).Observe(time.Since(start).Seconds())
+}
+
+// ObserveActionHandle records a transaction handled by the handler of the action
+func ObserveActionHandle(network, action string, start time.Time, err error) {
+	ActionHandleTotal.WithLabelValues(network, action).Inc()
+	ActionHandleDuration.WithLabelValues(network, action).Observe(time.Since(start).Seconds())
+	if err != nil {
+		ActionHandleErrors.WithLabelValues(network, action).Inc()
+	}
+}
+
+func SetBlockNumber(network string, current, tip uint64) {
+	CurrentBlockNumber.WithLabelValues(network).Set(float64(current))
+	TipBlockNumber.WithLabelValues(network).Set(float64(tip))
+	if tip > current {
+		BlockLag.WithLabelValues(network).Set(float64(tip - current))
+	} else {
+		BlockLag.WithLabelValues(network).Set(0)
+	}
+}
+
+func SetTokenPriceRefreshed() {
+	atomic.StoreInt64(&tokenPriceRefreshedAt, time.Now().UnixNano())
+}
+
+func ObserveHttp(method, path string, code int, start time.Time) {
+	HttpRequestsTotal.WithLabelValues(method, path, strconv.Itoa(code)).Inc()
+	HttpRequestDuration.WithLabelValues(method, path).Observe(time.Since(start).Seconds())
+}
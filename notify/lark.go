@@ -52,3 +52,11 @@ func SendLarkTextNotify(url, title, text string) error {
 	}
 	return nil
 }
+
+type LarkNotifier struct {
+	Url string
+}
+
+func (l *LarkNotifier) Notify(msg Message) error {
+	return SendLarkTextNotify(l.Url, msg.Title, msg.Body)
+}
@@ -0,0 +1,275 @@
+package notify
+
+import (
+	"bytes"
+	"das_database/config"
+	"fmt"
+	"github.com/scorpiotzh/mylog"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+var log = mylog.NewLogger("notify", mylog.LevelDebug)
+
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarn
+	SeverityError
+	SeverityCritical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarn:
+		return "warn"
+	case SeverityError:
+		return "error"
+	case SeverityCritical:
+		return "critical"
+	}
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(s) {
+	case "info":
+		return SeverityInfo, nil
+	case "", "warn":
+		return SeverityWarn, nil
+	case "error":
+		return SeverityError, nil
+	case "critical":
+		return SeverityCritical, nil
+	}
+	return 0, fmt.Errorf("unknown severity: %s", s)
+}
+
+type Message struct {
+	Severity Severity
+	Title    string
+	Text     string
+	// Key identifies repeats of the same alert, they are sent once per dedup window
+	Key  string
+	Time time.Time
+	// Suppressed is the number of repeats dropped since the last one sent
+	Suppressed int
+	// Body is Title and Text rendered by the template of the backend
+	Body string
+}
+
+// Notifier is a backend delivering the rendered messages
+type Notifier interface {
+	Notify(msg Message) error
+}
+
+const defaultTemplate = `[{{.Severity}}] {{.Title}}
+{{.Text}}{{if .Suppressed}}
+({{.Suppressed}} similar messages suppressed){{end}}`
+
+type route struct {
+	name        string
+	notifier    Notifier
+	minSeverity Severity
+	tmpl        *template.Template
+	limiter     *rateLimiter
+}
+
+type dedupEntry struct {
+	sentAt     time.Time
+	suppressed int
+}
+
+// Router sends the messages to the backends accepting their severity,
+// dropping repeats of a key within the dedup window and messages over the rate limit of a backend
+type Router struct {
+	routes      []*route
+	dedupWindow time.Duration
+
+	lock  sync.Mutex
+	dedup map[string]*dedupEntry
+}
+
+func NewRouter(dedupWindow time.Duration) *Router {
+	return &Router{dedupWindow: dedupWindow, dedup: make(map[string]*dedupEntry)}
+}
+
+// AddNotifier routes the messages of at least minSeverity to the notifier,
+// tmpl is a text/template over Message, the default one is used if empty,
+// rateLimit is the max number of messages per minute, 0 means unlimited
+func (r *Router) AddNotifier(name string, notifier Notifier, minSeverity Severity, tmpl string, rateLimit int) error {
+	if tmpl == "" {
+		tmpl = defaultTemplate
+	}
+	t, err := template.New(name).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("template parse err: %s", err.Error())
+	}
+	r.routes = append(r.routes, &route{
+		name:        name,
+		notifier:    notifier,
+		minSeverity: minSeverity,
+		tmpl:        t,
+		limiter:     newRateLimiter(rateLimit, time.Minute),
+	})
+	return nil
+}
+
+// allow checks the dedup window of the key and returns the number of repeats suppressed before
+func (r *Router) allow(msg Message) (bool, int) {
+	if msg.Key == "" || r.dedupWindow <= 0 {
+		return true, 0
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if e, ok := r.dedup[msg.Key]; ok && msg.Time.Sub(e.sentAt) < r.dedupWindow {
+		e.suppressed++
+		return false, 0
+	}
+	suppressed := 0
+	if e, ok := r.dedup[msg.Key]; ok {
+		suppressed = e.suppressed
+	}
+	r.dedup[msg.Key] = &dedupEntry{sentAt: msg.Time}
+	// forget the keys whose window is over
+	if len(r.dedup) > 1000 {
+		for k, e := range r.dedup {
+			if msg.Time.Sub(e.sentAt) >= r.dedupWindow {
+				delete(r.dedup, k)
+			}
+		}
+	}
+	return true, suppressed
+}
+
+// Send delivers the message synchronously, the errors of the backends are logged
+func (r *Router) Send(msg Message) {
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+	ok, suppressed := r.allow(msg)
+	if !ok {
+		return
+	}
+	msg.Suppressed = suppressed
+	for _, rt := range r.routes {
+		if msg.Severity < rt.minSeverity {
+			continue
+		}
+		if !rt.limiter.allow(msg.Time) {
+			log.Warn("notify rate limited:", rt.name, msg.Title)
+			continue
+		}
+		var buf bytes.Buffer
+		if err := rt.tmpl.Execute(&buf, msg); err != nil {
+			log.Error("template execute err:", rt.name, err.Error())
+			continue
+		}
+		m := msg
+		m.Body = buf.String()
+		if err := rt.notifier.Notify(m); err != nil {
+			log.Error("Notify err:", rt.name, err.Error())
+		}
+	}
+}
+
+// rateLimiter allows at most limit events per fixed window
+type rateLimiter struct {
+	lock        sync.Mutex
+	limit       int
+	window      time.Duration
+	windowStart time.Time
+	count       int
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window}
+}
+
+func (l *rateLimiter) allow(now time.Time) bool {
+	if l.limit <= 0 {
+		return true
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if now.Sub(l.windowStart) >= l.window {
+		l.windowStart = now
+		l.count = 0
+	}
+	if l.count >= l.limit {
+		return false
+	}
+	l.count++
+	return true
+}
+
+var defaultRouter = NewRouter(0)
+
+// Init builds the default router from the notice config
+func Init(cfg config.Notice) error {
+	router := NewRouter(time.Duration(cfg.DedupWindow) * time.Second)
+	if cfg.WebhookLarkErr != "" {
+		if err := router.AddNotifier("lark", &LarkNotifier{Url: cfg.WebhookLarkErr}, SeverityError, "", cfg.RateLimit); err != nil {
+			return err
+		}
+	}
+	for i, v := range cfg.Notifiers {
+		notifier, err := newNotifier(v)
+		if err != nil {
+			return fmt.Errorf("notifiers[%d] err: %s", i, err.Error())
+		}
+		minSeverity, err := ParseSeverity(v.MinSeverity)
+		if err != nil {
+			return fmt.Errorf("notifiers[%d] err: %s", i, err.Error())
+		}
+		rateLimit := v.RateLimit
+		if rateLimit == 0 {
+			rateLimit = cfg.RateLimit
+		}
+		if err := router.AddNotifier(fmt.Sprintf("%s[%d]", v.Type, i), notifier, minSeverity, v.Template, rateLimit); err != nil {
+			return fmt.Errorf("notifiers[%d] err: %s", i, err.Error())
+		}
+	}
+	defaultRouter = router
+	return nil
+}
+
+func newNotifier(cfg config.Notifier) (Notifier, error) {
+	switch cfg.Type {
+	case "lark":
+		return &LarkNotifier{Url: cfg.Url}, nil
+	case "slack":
+		return &SlackNotifier{Url: cfg.Url}, nil
+	case "discord":
+		return &DiscordNotifier{Url: cfg.Url}, nil
+	case "telegram":
+		return &TelegramNotifier{BotToken: cfg.BotToken, ChatId: cfg.ChatId}, nil
+	case "webhook":
+		return &WebhookNotifier{Url: cfg.Url}, nil
+	case "smtp":
+		return &SmtpNotifier{
+			Addr:     cfg.SmtpAddr,
+			Username: cfg.Username,
+			Password: cfg.Password,
+			From:     cfg.From,
+			To:       cfg.To,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown notifier type: %s", cfg.Type)
+}
+
+// Send delivers the message through the default router in the background
+func Send(msg Message) {
+	router := defaultRouter
+	go router.Send(msg)
+}
+
+// SendSync delivers the message through the default router before returning, for the commands exiting right after
+func SendSync(msg Message) {
+	defaultRouter.Send(msg)
+}
@@ -19,3 +19,42 @@ func TestSendLarkTextNotify(t *testing.T) {
 		t.Log("SendLarkTextNotify err:", err.Error())
 	}
 }
+
+type testNotifier struct {
+	msgs []Message
+}
+
+func (n *testNotifier) Notify(msg Message) error {
+	n.msgs = append(n.msgs, msg)
+	return nil
+}
+
+func TestRouter(t *testing.T) {
+	router := NewRouter(time.Minute)
+	all, critical := &testNotifier{}, &testNotifier{}
+	if err := router.AddNotifier("all", all, SeverityInfo, "", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := router.AddNotifier("critical", critical, SeverityCritical, "{{.Title}}|{{.Suppressed}}", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	router.Send(Message{Severity: SeverityCritical, Title: "t", Key: "k", Time: now})
+	router.Send(Message{Severity: SeverityCritical, Title: "t", Key: "k", Time: now.Add(time.Second)})
+	router.Send(Message{Severity: SeverityCritical, Title: "t", Key: "k", Time: now.Add(time.Minute)})
+	if len(critical.msgs) != 2 {
+		t.Fatal("dedup failed:", len(critical.msgs))
+	} else if critical.msgs[1].Body != "t|1" {
+		t.Fatal("template failed:", critical.msgs[1].Body)
+	}
+
+	for i := 0; i < 3; i++ {
+		router.Send(Message{Severity: SeverityInfo, Title: "info", Time: now.Add(time.Minute)})
+	}
+	if len(critical.msgs) != 2 {
+		t.Fatal("severity routing failed:", len(critical.msgs))
+	} else if len(all.msgs) != 4 { // 2 critical + 2 info within the limit of 3 per minute
+		t.Fatal("rate limit failed:", len(all.msgs))
+	}
+}
@@ -0,0 +1,40 @@
+package notify
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+type SmtpNotifier struct {
+	Addr     string // host:port
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func (s *SmtpNotifier) Notify(msg Message) error {
+	if s.Addr == "" || len(s.To) == 0 {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return fmt.Errorf("SplitHostPort err: %s", err.Error())
+	}
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	subject := fmt.Sprintf("[%s] %s", msg.Severity, msg.Title)
+	body := "From: " + s.From + "\r\n" +
+		"To: " + strings.Join(s.To, ",") + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + strings.ReplaceAll(msg.Body, "\n", "\r\n") + "\r\n"
+	if err := smtp.SendMail(s.Addr, auth, s.From, s.To, []byte(body)); err != nil {
+		return fmt.Errorf("SendMail err: %s", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"fmt"
+	"github.com/parnurzeal/gorequest"
+	"net/http"
+	"time"
+)
+
+func postJson(url string, data interface{}) error {
+	if url == "" {
+		return nil
+	}
+	resp, body, errs := gorequest.New().Post(url).Timeout(time.Second * 10).SendStruct(data).End()
+	if len(errs) > 0 {
+		return fmt.Errorf("errs:%v", errs)
+	} else if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("http code:%d %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// SlackNotifier posts to a slack incoming webhook
+type SlackNotifier struct {
+	Url string
+}
+
+func (s *SlackNotifier) Notify(msg Message) error {
+	return postJson(s.Url, map[string]string{"text": msg.Body})
+}
+
+const discordMaxContent = 2000
+
+// DiscordNotifier posts to a discord channel webhook
+type DiscordNotifier struct {
+	Url string
+}
+
+func (d *DiscordNotifier) Notify(msg Message) error {
+	content := msg.Body
+	if r := []rune(content); len(r) > discordMaxContent {
+		content = string(r[:discordMaxContent])
+	}
+	return postJson(d.Url, map[string]string{"content": content})
+}
+
+// TelegramNotifier sends through the sendMessage api of a telegram bot
+type TelegramNotifier struct {
+	BotToken string
+	ChatId   string
+}
+
+func (t *TelegramNotifier) Notify(msg Message) error {
+	if t.BotToken == "" || t.ChatId == "" {
+		return nil
+	}
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.BotToken)
+	return postJson(url, map[string]string{"chat_id": t.ChatId, "text": msg.Body})
+}
+
+type WebhookData struct {
+	Severity   string `json:"severity"`
+	Title      string `json:"title"`
+	Text       string `json:"text"`
+	Key        string `json:"key"`
+	Timestamp  int64  `json:"timestamp"`
+	Suppressed int    `json:"suppressed"`
+	Body       string `json:"body"`
+}
+
+// WebhookNotifier posts the message as WebhookData
+type WebhookNotifier struct {
+	Url string
+}
+
+func (w *WebhookNotifier) Notify(msg Message) error {
+	return postJson(w.Url, WebhookData{
+		Severity:   msg.Severity.String(),
+		Title:      msg.Title,
+		Text:       msg.Text,
+		Key:        msg.Key,
+		Timestamp:  msg.Time.Unix(),
+		Suppressed: msg.Suppressed,
+		Body:       msg.Body,
+	})
+}
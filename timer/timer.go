@@ -12,10 +12,12 @@ import (
 var log = mylog.NewLogger("timer", mylog.LevelDebug)
 
 type ParserTimer struct {
-	DbDao   *dao.DbDao
-	Ctx     context.Context
-	Wg      *sync.WaitGroup
-	DasCore *core.DasCore
+	Network    string // name of the network in the config, for its verify section
+	DbDao      *dao.DbDao
+	Ctx        context.Context
+	Wg         *sync.WaitGroup
+	DasCore    *core.DasCore
+	ConfirmNum uint64 // chain.confirm_num of the network, see waitParser
 }
 
 func (p *ParserTimer) RunUpdateTokenPrice() {
@@ -22,7 +22,7 @@ func getInit() (*dao.DbDao, error) {
 	if err != nil {
 		return nil, fmt.Errorf("NewGormDataBase err:%s", err.Error())
 	}
-	dbDao, err := dao.Initialize(db)
+	dbDao, err := dao.Initialize(db, config.Cfg.NetworkName())
 	if err != nil {
 		return nil, fmt.Errorf("Initialize err:%s ", err.Error())
 	}
@@ -2,6 +2,7 @@ package timer
 
 import (
 	"das_database/dao"
+	"das_database/metrics"
 	"github.com/scorpiotzh/toolib"
 	"github.com/shopspring/decimal"
 	"strings"
@@ -72,6 +73,8 @@ func (p *ParserTimer) updateTokenPriceInfoList() {
 		}
 		if err := p.DbDao.UpdateTokenPriceInfoList(tokenList); err != nil {
 			log.Error("UpdateTokenPriceInfoList err:", err.Error())
+		} else {
+			metrics.SetTokenPriceRefreshed()
 		}
 	}
 }
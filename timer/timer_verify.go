@@ -0,0 +1,491 @@
+package timer
+
+import (
+	"das_database/config"
+	"das_database/dao"
+	"das_database/notify"
+	"fmt"
+	"github.com/dotbitHQ/das-lib/common"
+	"github.com/dotbitHQ/das-lib/witness"
+	"github.com/nervosnetwork/ckb-sdk-go/types"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	verifyBatchSize        = 100
+	verifyDefaultSample    = 200
+	verifyDefaultInterval  = 3600
+	verifyRecheckTimeout   = time.Minute * 5
+	verifyRecheckPollDelay = time.Second * 10
+)
+
+type VerifyParams struct {
+	Full       bool     // scan every row, otherwise SampleSize rows of each table from a random id
+	SampleSize int      // verifyDefaultSample if 0
+	Tables     []string // t_account_info, t_offer_info, t_trade_info, t_reverse_info, all if empty
+}
+
+type VerifyResult struct {
+	RunId   string
+	Checked map[string]int // rows checked per table
+	Errors  int            // rows which could not be checked
+	Reports []dao.TableVerifyReport
+}
+
+// verifyTarget is an indexed row, check compares it with its cell on chain
+type verifyTarget struct {
+	id    uint64
+	check func() ([]dao.TableVerifyReport, error)
+}
+
+type verifyTable struct {
+	name string
+	list func(sample bool, lastId uint64, limit int) ([]verifyTarget, error)
+	get  func(id uint64) (*verifyTarget, error) // nil if the row is gone
+}
+
+type verifyRun struct {
+	p       *ParserTimer
+	txCache map[string]*types.Transaction
+}
+
+// RunVerify compares the indexed rows with the chain every verify.interval of the network and alerts on discrepancies
+func (p *ParserTimer) RunVerify() {
+	cfg := config.Cfg.Policy(p.Network).Verify
+	if !cfg.Enable {
+		return
+	}
+	interval := time.Duration(cfg.Interval) * time.Second
+	if interval == 0 {
+		interval = verifyDefaultInterval * time.Second
+	}
+	tickerVerify := time.NewTicker(interval)
+	p.Wg.Add(1)
+	go func() {
+		for {
+			select {
+			case <-tickerVerify.C:
+				cfg := config.Cfg.Policy(p.Network).Verify
+				log.Info("RunVerify start ...", time.Now().Format("2006-01-02 15:04:05"))
+				if res, err := p.Verify(VerifyParams{Full: cfg.Full, SampleSize: cfg.SampleSize}); err != nil {
+					log.Error("Verify err:", err.Error())
+				} else {
+					log.Info("RunVerify end ...", res.RunId, res.Checked, res.Errors, len(res.Reports))
+				}
+			case <-p.Ctx.Done():
+				tickerVerify.Stop()
+				p.Wg.Done()
+				return
+			}
+		}
+	}()
+}
+
+// Verify checks that the outpoints of the accounts, offers, sale listings and reverse records are live cells
+// and that their owner, manager, status, expiry, records or price match the cells.
+// The parser stays confirm_num blocks behind the node, so the rows found different are checked again
+// once it has indexed up to confirm_num blocks below the tip seen at the start, only the ones still different are reported.
+func (p *ParserTimer) Verify(params VerifyParams) (*VerifyResult, error) {
+	if params.SampleSize <= 0 {
+		params.SampleSize = verifyDefaultSample
+	}
+	tip, err := p.DasCore.Client().GetTipBlockNumber(p.Ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetTipBlockNumber err: %s", err.Error())
+	}
+	r := verifyRun{p: p, txCache: make(map[string]*types.Transaction)}
+	res := VerifyResult{
+		RunId:   time.Now().Format("20060102150405"),
+		Checked: make(map[string]int),
+	}
+
+	suspects, suspectNum := make(map[string][]uint64), 0
+	var tables []verifyTable
+	for _, t := range r.tables() {
+		if len(params.Tables) > 0 && !containsString(params.Tables, t.name) {
+			continue
+		}
+		tables = append(tables, t)
+		ids, checked, errNum, err := r.scan(t, params)
+		res.Checked[t.name] += checked
+		res.Errors += errNum
+		if err != nil {
+			return nil, fmt.Errorf("scan %s err: %s", t.name, err.Error())
+		}
+		suspects[t.name] = ids
+		suspectNum += len(ids)
+	}
+
+	if suspectNum > 0 {
+		r.waitParser(tip)
+	}
+	for _, t := range tables {
+		for _, id := range suspects[t.name] {
+			r.txCache = make(map[string]*types.Transaction)
+			target, err := t.get(id)
+			if err != nil {
+				return nil, fmt.Errorf("reload %s err: %s", t.name, err.Error())
+			} else if target == nil {
+				continue
+			}
+			reports, err := target.check()
+			if err != nil {
+				log.Warn("verify recheck err:", t.name, id, err.Error())
+				res.Errors++
+				continue
+			}
+			for i := range reports {
+				reports[i].RunId = res.RunId
+				reports[i].CheckTable = t.name
+				reports[i].RowId = id
+			}
+			res.Reports = append(res.Reports, reports...)
+		}
+	}
+
+	if err = p.DbDao.CreateVerifyReport(res.Reports); err != nil {
+		return nil, fmt.Errorf("CreateVerifyReport err: %s", err.Error())
+	}
+	if len(res.Reports) > 0 {
+		notify.SendSync(notify.Message{
+			Severity: notify.SeverityError,
+			Title:    "Verify: indexed rows differ from chain",
+			Text:     verifySummary(&res),
+			Key:      "verify",
+		})
+	}
+	return &res, nil
+}
+
+// scan checks the rows of the table and returns the ids of the ones found different
+func (r *verifyRun) scan(t verifyTable, params VerifyParams) (suspects []uint64, checked, errNum int, err error) {
+	lastId := uint64(0)
+	for {
+		if err = r.p.Ctx.Err(); err != nil {
+			return
+		}
+		limit := verifyBatchSize
+		if !params.Full {
+			limit = params.SampleSize
+		}
+		list, e := t.list(!params.Full, lastId, limit)
+		if e != nil {
+			err = e
+			return
+		}
+		// the txs of a batch are often shared, e.g. by the accounts of a proposal
+		r.txCache = make(map[string]*types.Transaction)
+		for _, v := range list {
+			lastId = v.id
+			checked++
+			reports, e := v.check()
+			if e != nil {
+				log.Warn("verify check err:", t.name, v.id, e.Error())
+				errNum++
+			} else if len(reports) > 0 {
+				suspects = append(suspects, v.id)
+			}
+		}
+		if !params.Full || len(list) < limit {
+			return
+		}
+	}
+}
+
+// waitParser waits for the parser to index the block tip - confirm_num, if it is running,
+// it never gets closer to the tip than that
+func (r *verifyRun) waitParser(tip uint64) {
+	if tip > r.p.ConfirmNum {
+		tip -= r.p.ConfirmNum
+	} else {
+		tip = 0
+	}
+	deadline := time.Now().Add(verifyRecheckTimeout)
+	for time.Now().Before(deadline) {
+		block, err := r.p.DbDao.FindBlockInfo()
+		if err != nil {
+			log.Error("FindBlockInfo err:", err.Error())
+		} else if block.BlockNumber >= tip {
+			return
+		}
+		select {
+		case <-time.After(verifyRecheckPollDelay):
+		case <-r.p.Ctx.Done():
+			return
+		}
+	}
+	log.Warn("verify: the parser did not reach the tip, recheck anyway:", tip)
+}
+
+func (r *verifyRun) tables() []verifyTable {
+	dbDao := r.p.DbDao
+	return []verifyTable{
+		{
+			name: dao.TableNameAccountInfo,
+			list: func(sample bool, lastId uint64, limit int) (targets []verifyTarget, err error) {
+				list, err := dbDao.FindVerifyAccountList(sample, lastId, limit)
+				for i := range list {
+					targets = append(targets, r.accountTarget(list[i]))
+				}
+				return
+			},
+			get: func(id uint64) (*verifyTarget, error) {
+				var row dao.TableAccountInfo
+				if err := dbDao.FindVerifyRow(&row, id); err != nil || row.Id == 0 {
+					return nil, err
+				}
+				target := r.accountTarget(row)
+				return &target, nil
+			},
+		},
+		{
+			name: dao.TableNameOfferInfo,
+			list: func(sample bool, lastId uint64, limit int) (targets []verifyTarget, err error) {
+				list, err := dbDao.FindVerifyOfferList(sample, lastId, limit)
+				for i := range list {
+					targets = append(targets, r.offerTarget(list[i]))
+				}
+				return
+			},
+			get: func(id uint64) (*verifyTarget, error) {
+				var row dao.TableOfferInfo
+				if err := dbDao.FindVerifyRow(&row, id); err != nil || row.Id == 0 {
+					return nil, err
+				}
+				target := r.offerTarget(row)
+				return &target, nil
+			},
+		},
+		{
+			name: dao.TableNameTradeInfo,
+			list: func(sample bool, lastId uint64, limit int) (targets []verifyTarget, err error) {
+				list, err := dbDao.FindVerifyTradeList(sample, lastId, limit)
+				for i := range list {
+					targets = append(targets, r.tradeTarget(list[i]))
+				}
+				return
+			},
+			get: func(id uint64) (*verifyTarget, error) {
+				var row dao.TableTradeInfo
+				if err := dbDao.FindVerifyRow(&row, id); err != nil || row.Id == 0 {
+					return nil, err
+				}
+				target := r.tradeTarget(row)
+				return &target, nil
+			},
+		},
+		{
+			name: dao.TableNameReverseInfo,
+			list: func(sample bool, lastId uint64, limit int) (targets []verifyTarget, err error) {
+				list, err := dbDao.FindVerifyReverseList(sample, lastId, limit)
+				for i := range list {
+					targets = append(targets, r.reverseTarget(list[i]))
+				}
+				return
+			},
+			get: func(id uint64) (*verifyTarget, error) {
+				var row dao.TableReverseInfo
+				if err := dbDao.FindVerifyRow(&row, id); err != nil || row.Id == 0 {
+					return nil, err
+				}
+				target := r.reverseTarget(row)
+				return &target, nil
+			},
+		},
+	}
+}
+
+// liveOutput returns the tx of the outpoint and its index if the cell is live, or the report of the dead cell
+func (r *verifyRun) liveOutput(outpoint string, report dao.TableVerifyReport) (*types.Transaction, uint, []dao.TableVerifyReport, error) {
+	op := common.String2OutPointStruct(outpoint)
+	cell, err := r.p.DasCore.Client().GetLiveCell(r.p.Ctx, op, false)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("GetLiveCell err: %s", err.Error())
+	}
+	if cell.Status != "live" {
+		report.Field = "live"
+		report.DbValue = "live"
+		report.ChainValue = cell.Status
+		return nil, 0, []dao.TableVerifyReport{report}, nil
+	}
+	hash := op.TxHash.Hex()
+	tx, ok := r.txCache[hash]
+	if !ok {
+		res, err := r.p.DasCore.Client().GetTransaction(r.p.Ctx, op.TxHash)
+		if err != nil {
+			return nil, 0, nil, fmt.Errorf("GetTransaction err: %s", err.Error())
+		}
+		tx = res.Transaction
+		r.txCache[hash] = tx
+	}
+	if int(op.Index) >= len(tx.Outputs) {
+		return nil, 0, nil, fmt.Errorf("output index out of range: %s", outpoint)
+	}
+	return tx, uint(op.Index), nil, nil
+}
+
+func (r *verifyRun) accountTarget(row dao.TableAccountInfo) verifyTarget {
+	return verifyTarget{id: row.Id, check: func() ([]dao.TableVerifyReport, error) {
+		base := dao.TableVerifyReport{AccountId: row.AccountId, Account: row.Account, Outpoint: row.Outpoint}
+		tx, index, reports, err := r.liveOutput(row.Outpoint, base)
+		if err != nil || reports != nil {
+			return reports, err
+		}
+		ownerHex, managerHex, err := r.p.DasCore.Daf().ArgsToHex(tx.Outputs[index].Lock.Args)
+		if err != nil {
+			return nil, fmt.Errorf("ArgsToHex err: %s", err.Error())
+		}
+		reports = appendDiff(reports, base, "owner", addressString(row.OwnerChainType, row.Owner), addressString(ownerHex.ChainType, ownerHex.AddressHex))
+		reports = appendDiff(reports, base, "manager", addressString(row.ManagerChainType, row.Manager), addressString(managerHex.ChainType, managerHex.AddressHex))
+
+		builderMap, err := witness.AccountIdCellDataBuilderFromTx(tx, common.DataTypeNew)
+		if err != nil {
+			return nil, fmt.Errorf("AccountIdCellDataBuilderFromTx err: %s", err.Error())
+		}
+		builder, ok := builderMap[row.AccountId]
+		if !ok || uint(builder.Index) != index {
+			return appendDiff(reports, base, "account_id", row.AccountId, ""), nil
+		}
+		reports = appendDiff(reports, base, "status", strconv.Itoa(int(row.Status)), strconv.Itoa(int(builder.Status)))
+		reports = appendDiff(reports, base, "expired_at", strconv.FormatUint(row.ExpiredAt, 10), strconv.FormatUint(builder.ExpiredAt, 10))
+
+		records, err := r.p.DbDao.FindRecordsByAccountId(row.AccountId)
+		if err != nil {
+			return nil, fmt.Errorf("FindRecordsByAccountId err: %s", err.Error())
+		}
+		var dbRecords, chainRecords []string
+		for _, v := range records {
+			dbRecords = append(dbRecords, strings.Join([]string{v.Type, v.Key, v.Label, v.Value, v.Ttl}, ","))
+		}
+		for _, v := range builder.Records {
+			chainRecords = append(chainRecords, strings.Join([]string{v.Type, v.Key, v.Label, v.Value, strconv.FormatUint(uint64(v.TTL), 10)}, ","))
+		}
+		sort.Strings(dbRecords)
+		sort.Strings(chainRecords)
+		reports = appendDiff(reports, base, "records", strings.Join(dbRecords, "\n"), strings.Join(chainRecords, "\n"))
+		return reports, nil
+	}}
+}
+
+func (r *verifyRun) offerTarget(row dao.TableOfferInfo) verifyTarget {
+	return verifyTarget{id: row.Id, check: func() ([]dao.TableVerifyReport, error) {
+		base := dao.TableVerifyReport{AccountId: row.AccountId, Account: row.Account, Outpoint: row.Outpoint}
+		tx, index, reports, err := r.liveOutput(row.Outpoint, base)
+		if err != nil || reports != nil {
+			return reports, err
+		}
+		ownerHex, _, err := r.p.DasCore.Daf().ArgsToHex(tx.Outputs[index].Lock.Args)
+		if err != nil {
+			return nil, fmt.Errorf("ArgsToHex err: %s", err.Error())
+		}
+		reports = appendDiff(reports, base, "owner", addressString(row.ChainType, row.Address), addressString(ownerHex.ChainType, ownerHex.AddressHex))
+
+		builderMap, err := witness.OfferCellDataBuilderMapFromTx(tx, common.DataTypeNew)
+		if err != nil {
+			return nil, fmt.Errorf("OfferCellDataBuilderMapFromTx err: %s", err.Error())
+		}
+		var builder *witness.OfferCellBuilder
+		for _, v := range builderMap {
+			if uint(v.Index) == index {
+				builder = v
+			}
+		}
+		if builder == nil {
+			return appendDiff(reports, base, "account", row.Account, ""), nil
+		}
+		reports = appendDiff(reports, base, "account", row.Account, builder.Account)
+		reports = appendDiff(reports, base, "price", strconv.FormatUint(row.Price, 10), strconv.FormatUint(builder.Price, 10))
+		return reports, nil
+	}}
+}
+
+func (r *verifyRun) tradeTarget(row dao.TableTradeInfo) verifyTarget {
+	return verifyTarget{id: row.Id, check: func() ([]dao.TableVerifyReport, error) {
+		base := dao.TableVerifyReport{AccountId: row.AccountId, Account: row.Account, Outpoint: row.Outpoint}
+		tx, index, reports, err := r.liveOutput(row.Outpoint, base)
+		if err != nil || reports != nil {
+			return reports, err
+		}
+		ownerHex, _, err := r.p.DasCore.Daf().ArgsToHex(tx.Outputs[index].Lock.Args)
+		if err != nil {
+			return nil, fmt.Errorf("ArgsToHex err: %s", err.Error())
+		}
+		reports = appendDiff(reports, base, "owner", addressString(row.OwnerChainType, row.OwnerAddress), addressString(ownerHex.ChainType, ownerHex.AddressHex))
+
+		builder, err := witness.AccountSaleCellDataBuilderFromTx(tx, common.DataTypeNew)
+		if err != nil {
+			return nil, fmt.Errorf("AccountSaleCellDataBuilderFromTx err: %s", err.Error())
+		}
+		if uint(builder.Index) != index {
+			return appendDiff(reports, base, "account_id", row.AccountId, ""), nil
+		}
+		reports = appendDiff(reports, base, "account_id", row.AccountId, builder.AccountId)
+		reports = appendDiff(reports, base, "price", strconv.FormatUint(row.PriceCkb, 10), strconv.FormatUint(builder.Price, 10))
+		return reports, nil
+	}}
+}
+
+func (r *verifyRun) reverseTarget(row dao.TableReverseInfo) verifyTarget {
+	return verifyTarget{id: row.Id, check: func() ([]dao.TableVerifyReport, error) {
+		base := dao.TableVerifyReport{AccountId: row.AccountId, Account: row.Account, Outpoint: row.Outpoint}
+		tx, index, reports, err := r.liveOutput(row.Outpoint, base)
+		if err != nil || reports != nil {
+			return reports, err
+		}
+		ownerHex, _, err := r.p.DasCore.Daf().ArgsToHex(tx.Outputs[index].Lock.Args)
+		if err != nil {
+			return nil, fmt.Errorf("ArgsToHex err: %s", err.Error())
+		}
+		reports = appendDiff(reports, base, "owner", addressString(row.ChainType, row.Address), addressString(ownerHex.ChainType, ownerHex.AddressHex))
+		if int(index) < len(tx.OutputsData) {
+			reports = appendDiff(reports, base, "account", row.Account, string(tx.OutputsData[index]))
+		}
+		return reports, nil
+	}}
+}
+
+func appendDiff(reports []dao.TableVerifyReport, base dao.TableVerifyReport, field, dbValue, chainValue string) []dao.TableVerifyReport {
+	if dbValue == chainValue {
+		return reports
+	}
+	base.Field = field
+	base.DbValue = dbValue
+	base.ChainValue = chainValue
+	return append(reports, base)
+}
+
+func addressString(chainType common.ChainType, address string) string {
+	return fmt.Sprintf("%d:%s", chainType, address)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// verifySummary counts the discrepancies per table and field
+func verifySummary(res *VerifyResult) string {
+	counts := make(map[string]int)
+	for _, v := range res.Reports {
+		counts[v.CheckTable+"."+v.Field]++
+	}
+	var keys []string
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "run_id: %s\n", res.RunId)
+	for _, k := range keys {
+		fmt.Fprintf(&sb, "%s: %d\n", k, counts[k])
+	}
+	sb.WriteString("see t_verify_report")
+	return sb.String()
+}